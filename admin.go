@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// isAdmin reports whether the request carries ADMIN_TOKEN as its bearer
+// token. With no ADMIN_TOKEN configured, everyone is an admin on the dev
+// platform, for local convenience, and nobody is anywhere else.
+func (a *apiConfig) isAdmin(req *http.Request) bool {
+	if a.adminToken == "" {
+		return a.platform == "dev"
+	}
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) == 1
+}
+
+// middlewareAdmin guards everything under /admin/ with isAdmin: no token
+// is a 401, any token but the admin one, like a user's, a 403.
+func (a *apiConfig) middlewareAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if strings.HasPrefix(req.URL.Path, "/admin/") && !a.isAdmin(req) {
+				log.Printf("in middlewareAdmin, refused %s %s from %s: missing or invalid admin token", req.Method, req.URL.Path, req.RemoteAddr)
+				if _, err := auth.GetBearerToken(req.Header); err == nil {
+					respondWithError(w, http.StatusForbidden, "Admin token required")
+					return
+				}
+				respondWithError(w, http.StatusUnauthorized, "Admin token required")
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+}
+
+// AdminUser is the moderation view of a user.
+type AdminUser struct {
+	User
+	Suspended bool `json:"suspended"`
+}
+
+func adminUserFromDB(dbUser database.User) AdminUser {
+	return AdminUser{
+		User:      userFromDB(dbUser),
+		Suspended: dbUser.Suspended,
+	}
+}
+
+// handlerListUsers pages through users oldest first, with ties broken by id
+// so pages don't shift between loads. X-Total-Count has the user count.
+func (a *apiConfig) handlerListUsers(w http.ResponseWriter, req *http.Request) {
+	listArgs := database.ListUsersParams{
+		Limit:  a.pageSizes.limit(req.URL.Query()),
+		Offset: pageOffset(req.URL.Query()),
+	}
+	setPageSize(w, listArgs.Limit)
+	dbUsers, err := a.dbQueries.ListUsers(req.Context(), listArgs)
+	if err != nil {
+		log.Printf("in handlerListUsers, unable to list users: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	total, err := a.dbQueries.CountUsers(req.Context())
+	if err != nil {
+		log.Printf("in handlerListUsers, unable to count users: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	users := []AdminUser{}
+	for _, dbUser := range dbUsers {
+		users = append(users, adminUserFromDB(dbUser))
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// handlerAdminCreateUser adds a user whether or not signups are open, for
+// closed instances where admins hand out accounts.
+func (a *apiConfig) handlerAdminCreateUser(w http.ResponseWriter, req *http.Request) {
+	type reqBody struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerAdminCreateUser, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	dbUser, ok := a.createUser(w, req, body.Email, body.Password, "")
+	if !ok {
+		return
+	}
+
+	log.Printf("user %s created by %s", dbUser.ID, req.RemoteAddr)
+	respondWithJSON(w, http.StatusCreated, adminUserFromDB(dbUser))
+}
+
+func (a *apiConfig) handlerSuspendUser(w http.ResponseWriter, req *http.Request) {
+	a.setSuspended(w, req, true)
+}
+
+func (a *apiConfig) handlerUnsuspendUser(w http.ResponseWriter, req *http.Request) {
+	a.setSuspended(w, req, false)
+}
+
+func (a *apiConfig) setSuspended(w http.ResponseWriter, req *http.Request, suspended bool) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in setSuspended, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	suspendArgs := database.SetUserSuspendedParams{
+		ID:        userID,
+		Suspended: suspended,
+	}
+	dbUser, err := a.dbQueries.SetUserSuspended(req.Context(), suspendArgs)
+	if err != nil {
+		log.Printf("in setSuspended, unable to update user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	log.Printf("user %s suspended=%t by %s", dbUser.ID, dbUser.Suspended, req.RemoteAddr)
+	respondWithJSON(w, http.StatusOK, adminUserFromDB(dbUser))
+}
+
+// handlerSetChirpyRed grants or takes away Chirpy Red by hand, for support
+// staff fixing up what the Polka webhook didn't.
+func (a *apiConfig) handlerSetChirpyRed(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerSetChirpyRed, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	type reqBody struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	err = decoder.Decode(&body)
+	if respondWithUnknownField(w, err) {
+		return
+	}
+	if err != nil || body.Enabled == nil {
+		log.Printf("in handlerSetChirpyRed, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "enabled must be true or false")
+		return
+	}
+
+	var dbUser database.User
+	if *body.Enabled {
+		dbUser, err = a.dbQueries.UpgradeUserChirpyRed(req.Context(), userID)
+	} else {
+		dbUser, err = a.dbQueries.DowngradeUserChirpyRed(req.Context(), userID)
+	}
+	if err != nil {
+		log.Printf("in handlerSetChirpyRed, unable to update user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	log.Printf("user %s is_chirpy_red=%t by %s", dbUser.ID, dbUser.IsChirpyRed, req.RemoteAddr)
+	respondWithJSON(w, http.StatusOK, adminUserFromDB(dbUser))
+}
+
+// handlerRevokeUserSessions logs a user out everywhere by revoking all of
+// their refresh tokens, for moderators acting on a ban. With ?suspend=true
+// the account is suspended in the same transaction.
+func (a *apiConfig) handlerRevokeUserSessions(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerRevokeUserSessions, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	suspend := req.URL.Query().Get("suspend") == "true"
+
+	var dbUser database.User
+	var revoked int64
+	err = a.runTx(req.Context(), func(q database.Querier) error {
+		var err error
+		dbUser, err = q.GetUser(req.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		revoked, err = q.RevokeAllRefreshTokensForUser(req.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		if suspend {
+			suspendArgs := database.SetUserSuspendedParams{
+				ID:        userID,
+				Suspended: true,
+			}
+			dbUser, err = q.SetUserSuspended(req.Context(), suspendArgs)
+		}
+		return err
+	})
+	if err != nil {
+		log.Printf("in handlerRevokeUserSessions, unable to revoke sessions: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	log.Printf("user %s: %d sessions revoked, suspended=%t by %s", dbUser.ID, revoked, dbUser.Suspended, req.RemoteAddr)
+
+	type response struct {
+		Revoked int64     `json:"revoked"`
+		User    AdminUser `json:"user"`
+	}
+	respondWithJSON(w, http.StatusOK, response{Revoked: revoked, User: adminUserFromDB(dbUser)})
+}
+
+// handlerAdminDeleteChirp removes any chirp, for moderators. Unlike authors,
+// they aren't held to the delete window.
+func (a *apiConfig) handlerAdminDeleteChirp(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerAdminDeleteChirp, could not parse chirp id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, err := a.dbQueries.GetChirp(req.Context(), chirpID); err != nil {
+		log.Printf("in handlerAdminDeleteChirp, could not get chirp: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := a.dbQueries.DeleteChirp(req.Context(), chirpID); err != nil {
+		log.Printf("in handlerAdminDeleteChirp, unable to delete chirp: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("chirp %s deleted by %s", chirpID, req.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+)
+
+func TestClosedSignups(t *testing.T) {
+	a, _, _, _ := newTestAPI(t)
+	params, err := auth.NewHashParams(auth.MinHashMemory, 1, 1)
+	if err != nil {
+		t.Fatalf("NewHashParams failed: %v", err)
+	}
+	a.hashParams = params
+	signup := func(handler http.HandlerFunc, email string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := `{"email":"` + email + `","password":"hunter2"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	if w := signup(a.handlerUsers, "open@example.com"); w.Code != http.StatusCreated {
+		t.Fatalf("expected an open signup to succeed, got %d %s", w.Code, w.Body)
+	}
+
+	a.allowSignups = false
+	if w := signup(a.handlerUsers, "closed@example.com"); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "Signups are closed") {
+		t.Fatalf("expected a closed signup to be forbidden, got %d %s", w.Code, w.Body)
+	}
+	if w := signup(a.handlerAdminCreateUser, "closed@example.com"); w.Code != http.StatusCreated {
+		t.Fatalf("expected an admin to create the user, got %d %s", w.Code, w.Body)
+	}
+	if w := signup(a.handlerAdminCreateUser, "closed@example.com"); w.Code != http.StatusConflict {
+		t.Fatalf("expected the same email again to conflict, got %d %s", w.Code, w.Body)
+	}
+}
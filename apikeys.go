@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// APIKey describes a key without the key itself, which is only ever in the
+// response that created it.
+type APIKey struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Key       string    `json:"key,omitempty"`
+}
+
+func apiKeyFromDB(dbKey database.ApiKey) APIKey {
+	return APIKey{
+		ID:        dbKey.ID,
+		CreatedAt: dbKey.CreatedAt,
+	}
+}
+
+func (a *apiConfig) handlerCreateAPIKey(w http.ResponseWriter, req *http.Request) {
+	//Authenticate, a key can't be used to mint more keys
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerCreateAPIKey, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	key, err := auth.MakeAPIKey()
+	if err != nil {
+		log.Printf("in handlerCreateAPIKey, unable to make key: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	createArgs := database.CreateAPIKeyParams{
+		UserID:  userID,
+		KeyHash: auth.HashAPIKey(key),
+	}
+	dbKey, err := a.dbQueries.CreateAPIKey(req.Context(), createArgs)
+	if err != nil {
+		log.Printf("in handlerCreateAPIKey, unable to create key: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	apiKey := apiKeyFromDB(dbKey)
+	apiKey.Key = key
+	respondWithJSON(w, http.StatusCreated, apiKey)
+}
+
+func (a *apiConfig) handlerGetAPIKeys(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerGetAPIKeys, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	dbKeys, err := a.dbQueries.ListAPIKeysForUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetAPIKeys, unable to list keys: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	apiKeys := []APIKey{}
+	for _, dbKey := range dbKeys {
+		apiKeys = append(apiKeys, apiKeyFromDB(dbKey))
+	}
+	respondWithJSON(w, http.StatusOK, apiKeys)
+}
+
+func (a *apiConfig) handlerDeleteAPIKey(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerDeleteAPIKey, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	keyID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerDeleteAPIKey, could not parse key id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//Only the user's own keys can be matched
+	deleteArgs := database.DeleteAPIKeyParams{
+		ID:     keyID,
+		UserID: userID,
+	}
+	deleted, err := a.dbQueries.DeleteAPIKey(req.Context(), deleteArgs)
+	if err != nil {
+		log.Printf("in handlerDeleteAPIKey, unable to delete key: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyChirping(t *testing.T) {
+	a, _, _, token := newTestAPI(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/me/api_keys", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	a.handlerCreateAPIKey(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create key: got %d, want %d", w.Code, http.StatusCreated)
+	}
+	var apiKey APIKey
+	if err := json.Unmarshal(w.Body.Bytes(), &apiKey); err != nil || apiKey.Key == "" {
+		t.Fatalf("expected the new key in %s, %v", w.Body, err)
+	}
+
+	postChirp := func() int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(`{"body":"from a script"}`))
+		req.Header.Set("Authorization", "ApiKey "+apiKey.Key)
+		w := httptest.NewRecorder()
+		a.handlerChirps(w, req)
+		return w.Code
+	}
+	if code := postChirp(); code != http.StatusCreated {
+		t.Errorf("chirp with a key: got %d, want %d", code, http.StatusCreated)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/me/api_keys/"+apiKey.ID.String(), nil)
+	req.SetPathValue("id", apiKey.ID.String())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	a.handlerDeleteAPIKey(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete key: got %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	if code := postChirp(); code != http.StatusUnauthorized {
+		t.Errorf("chirp with a revoked key: got %d, want %d", code, http.StatusUnauthorized)
+	}
+}
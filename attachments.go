@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+const (
+	maxAttachments         = 4
+	maxAttachmentURLLength = 2048
+)
+
+var attachmentTypes = map[string]bool{
+	"image/gif":  true,
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+type Attachment struct {
+	URL  string `json:"url"`
+	Type string `json:"type"`
+}
+
+// validateAttachments returns an error suitable for showing to the client.
+// With allowedHosts set, attachment URLs must point at one of them.
+func validateAttachments(attachments []Attachment, allowedHosts []string) error {
+	if len(attachments) > maxAttachments {
+		return fmt.Errorf("A chirp can have at most %d attachments", maxAttachments)
+	}
+
+	for _, attachment := range attachments {
+		if len(attachment.URL) > maxAttachmentURLLength {
+			return errors.New("Attachment URL is too long")
+		}
+		u, err := url.Parse(attachment.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Attachment URL must be http or https: %q", attachment.URL)
+		}
+		if len(allowedHosts) > 0 && !slices.Contains(allowedHosts, strings.ToLower(u.Hostname())) {
+			return fmt.Errorf("Attachment host is not allowed: %q", u.Hostname())
+		}
+		if !attachmentTypes[attachment.Type] {
+			return fmt.Errorf("Unsupported attachment type: %q", attachment.Type)
+		}
+	}
+	return nil
+}
+
+// loadAttachments fills in the attachments of chirps with one query.
+func loadAttachments(ctx context.Context, q database.Querier, chirps []Chirp) error {
+	byID := map[uuid.UUID]int{}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for i, chirp := range chirps {
+		byID[chirp.ID] = i
+		ids = append(ids, chirp.ID)
+	}
+
+	dbAttachments, err := q.GetChirpAttachments(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	//rows come back in position order per chirp
+	for _, dbAttachment := range dbAttachments {
+		i := byID[dbAttachment.ChirpID]
+		chirps[i].Attachments = append(chirps[i].Attachments, Attachment{
+			URL:  dbAttachment.Url,
+			Type: dbAttachment.Type,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// handlerAvailable tells a signup form whether an email is free. It only
+// ever answers with a boolean, never with the account. There are no
+// usernames yet, so email is the only thing to check.
+func (a *apiConfig) handlerAvailable(w http.ResponseWriter, req *http.Request) {
+	email := req.URL.Query().Get("email")
+	if email == "" {
+		respondWithError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	exists, err := a.readQueries.EmailExists(req.Context(), email)
+	if err != nil {
+		log.Printf("in handlerAvailable, unable to check email: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type response struct {
+		Available bool `json:"available"`
+	}
+	respondWithJSON(w, http.StatusOK, response{Available: !exists})
+}
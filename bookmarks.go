@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (a *apiConfig) handlerCreateBookmark(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerCreateBookmark, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Get chirp id
+	chirpID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerCreateBookmark, could not parse chirp id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//Does the chirp exist, as far as the user can tell?
+	chirp, err := a.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		log.Printf("in handlerCreateBookmark, could not get chirp: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+	visible, err := canView(req.Context(), a.dbQueries, uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		log.Printf("in handlerCreateBookmark, unable to check visibility: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !visible {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//Saving twice is a no-op
+	bookmarkArgs := database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+	err = a.dbQueries.CreateBookmark(req.Context(), bookmarkArgs)
+	if err != nil {
+		log.Printf("in handlerCreateBookmark, unable to create bookmark: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiConfig) handlerDeleteBookmark(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerDeleteBookmark, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Get chirp id
+	chirpID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerDeleteBookmark, could not parse chirp id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	deleteArgs := database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+	err = a.dbQueries.DeleteBookmark(req.Context(), deleteArgs)
+	if err != nil {
+		log.Printf("in handlerDeleteBookmark, unable to delete bookmark: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiConfig) handlerGetBookmarks(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerGetBookmarks, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	dbChirps, err := a.readQueries.GetBookmarkedChirps(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetBookmarks, unable to get bookmarked chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	chirps, err := chirpsFromDB(req.Context(), a.readQueries, uuid.NullUUID{UUID: userID, Valid: true}, dbChirps)
+	if err != nil {
+		log.Printf("in handlerGetBookmarks, unable to load chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
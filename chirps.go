@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+	"github.com/kbm-ky/chirpy/internal/lang"
+	"github.com/kbm-ky/chirpy/internal/profanity"
+)
+
+const (
+	defaultMinChirpLength = 1
+	defaultMaxChirpLength = 140
+	//Chirpy Red users can post longer chirps
+	defaultRedChirpLength = 280
+)
+
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:          dbChirp.ID,
+		CreatedAt:   dbChirp.CreatedAt,
+		UpdatedAt:   dbChirp.UpdatedAt,
+		Body:        dbChirp.Body,
+		UserID:      dbChirp.UserID,
+		Attachments: []Attachment{},
+		Visibility:  string(dbChirp.Visibility),
+		Lang:        dbChirp.Lang,
+	}
+	if dbChirp.QuotedChirpID.Valid {
+		chirp.QuotedChirp = &QuotedChirp{ID: dbChirp.QuotedChirpID.UUID}
+	}
+	chirp.ExpiresAt = nullTime(dbChirp.ExpiresAt)
+	return chirp
+}
+
+// chirpsFromDB converts dbChirps for a response, loading everything they
+// embed with one query per kind rather than per chirp. Pass the queries the
+// chirps were read with, so a write isn't followed by a stale replica read,
+// and the viewer they are for.
+func chirpsFromDB(ctx context.Context, q database.Querier, viewer uuid.NullUUID, dbChirps []database.Chirp) ([]Chirp, error) {
+	chirps := []Chirp{}
+	for _, dbChirp := range dbChirps {
+		chirps = append(chirps, chirpFromDB(dbChirp))
+	}
+	if len(chirps) == 0 {
+		return chirps, nil
+	}
+
+	if err := loadAttachments(ctx, q, chirps); err != nil {
+		return nil, err
+	}
+	if err := loadQuotes(ctx, q, viewer, chirps); err != nil {
+		return nil, err
+	}
+	return chirps, nil
+}
+
+// checkChirpLength trims the whitespace around body, which is never stored,
+// and returns it with a message for the client if what's left is empty, too
+// short or too long for the author's tier. Empty bodies are refused even
+// with no minimum set.
+func (a *apiConfig) checkChirpLength(body string, isChirpyRed bool) (string, string) {
+	maxLength := a.maxChirpLength
+	if isChirpyRed {
+		maxLength = a.redChirpLength
+	}
+
+	body = strings.TrimSpace(body)
+	switch {
+	case body == "":
+		return body, "Chirp is empty"
+	case len(body) < a.minChirpLength:
+		return body, "Chirp is too short"
+	case len(body) > maxLength:
+		return body, fmt.Sprintf("Chirp is too long, the limit is %d characters", maxLength)
+	}
+	return body, ""
+}
+
+// checkChirp applies the rules every chirp body must meet, whether it is
+// being validated, posted or edited: it trims and checks the length, then
+// masks or finds banned words. It returns the body as it would be stored, a
+// message for each rule it breaks, length first, and, in reject mode, the
+// banned words found.
+func (a *apiConfig) checkChirp(body string, isChirpyRed bool) (string, []string, []string) {
+	problems := []string{}
+	body, problem := a.checkChirpLength(body, isChirpyRed)
+	if problem != "" {
+		problems = append(problems, problem)
+	}
+
+	body, banned := a.filterProfanity(body)
+	if len(banned) > 0 {
+		problems = append(problems, "Chirp contains banned words")
+	}
+
+	return body, problems, banned
+}
+
+// validateAndCleanChirp is checkChirp for posting and editing, so an edit
+// can't sneak in what posting would refuse. It returns the body to store,
+// or answers the request with the first problem and reports false.
+func (a *apiConfig) validateAndCleanChirp(w http.ResponseWriter, body string, isChirpyRed bool) (string, bool) {
+	body, problems, banned := a.checkChirp(body, isChirpyRed)
+	if len(problems) == 0 {
+		return body, true
+	}
+
+	//a length problem comes first and is answered before any banned words
+	if len(banned) > 0 && len(problems) == 1 {
+		log.Printf("in validateAndCleanChirp, rejected chirp with banned words")
+		respondWithBannedWords(w, banned)
+		return "", false
+	}
+	log.Printf("in validateAndCleanChirp, %s", strings.ToLower(problems[0]))
+	respondWithError(w, http.StatusBadRequest, problems[0])
+	return "", false
+}
+
+// filterProfanity applies the profanity mode to body: in censor mode it
+// returns body with the banned words masked, in reject mode it returns the
+// banned words found, if any, and the chirp must not be posted.
+func (a *apiConfig) filterProfanity(body string) (string, []string) {
+	if a.profanity.Mode == profanity.Reject {
+		return body, a.profanity.Find(body)
+	}
+
+	//Clean also normalizes whitespace, so only take its result when needed
+	if cleanedBody, cleaned := a.profanity.Clean(body); cleaned {
+		return cleanedBody, nil
+	}
+	return body, nil
+}
+
+func respondWithBannedWords(w http.ResponseWriter, banned []string) {
+	type bannedResponse struct {
+		Error string   `json:"error"`
+		Words []string `json:"words"`
+	}
+	respondWithJSON(w, http.StatusBadRequest, bannedResponse{
+		Error: "Chirp contains banned words",
+		Words: banned,
+	})
+}
+
+// withinWindow reports whether now is within window of createdAt. A zero
+// window never closes.
+func withinWindow(createdAt time.Time, window time.Duration, now time.Time) bool {
+	return window == 0 || now.Sub(createdAt) <= window
+}
+
+func (a *apiConfig) handlerValidateChirp(w http.ResponseWriter, req *http.Request) {
+	type reqBody struct {
+		Body string `json:"body"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerValidateChirp, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	//Dry run only, nothing is saved
+	type validateResponse struct {
+		Valid       bool     `json:"valid"`
+		CleanedBody string   `json:"cleaned_body"`
+		Errors      []string `json:"errors"`
+		Words       []string `json:"words,omitempty"`
+	}
+
+	//a Red user saying who they are is checked against their limit
+	isChirpyRed := false
+	if viewer := a.viewer(req); viewer.Valid {
+		dbUser, err := a.readQueries.GetUser(req.Context(), viewer.UUID)
+		isChirpyRed = err == nil && dbUser.IsChirpyRed
+	}
+
+	cleanedBody, problems, banned := a.checkChirp(body.Body, isChirpyRed)
+	respondWithJSON(w, http.StatusOK, validateResponse{
+		Valid:       len(problems) == 0,
+		CleanedBody: cleanedBody,
+		Errors:      problems,
+		Words:       banned,
+	})
+}
+
+func (a *apiConfig) handlerEditChirp(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerEditChirp, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Get chirp id
+	chirpID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerEditChirp, could not parse chirp id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//decode request body
+	type reqBody struct {
+		Body string `json:"body"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerEditChirp, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	//Is user the author?
+	chirp, err := a.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		log.Printf("in handlerEditChirp, could not get chirp: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if userID != chirp.UserID {
+		log.Printf("in handlerEditChirp, user is not the author")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if !withinWindow(chirp.CreatedAt, a.editWindow, time.Now()) {
+		respondWithError(w, http.StatusForbidden, "Chirp can no longer be edited")
+		return
+	}
+
+	//Optional If-Match with the updated_at the client last saw
+	ifUpdatedAt, err := parseIfMatch(req.Header.Get("If-Match"))
+	if err != nil {
+		log.Printf("in handlerEditChirp, bad If-Match: %v", err)
+		respondWithError(w, http.StatusBadRequest, "If-Match must be the chirp's updated_at")
+		return
+	}
+
+	//Same rules as posting a new chirp
+	author, err := a.dbQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerEditChirp, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var ok bool
+	body.Body, ok = a.validateAndCleanChirp(w, body.Body, author.IsChirpyRed)
+	if !ok {
+		return
+	}
+
+	//Update and record the new version together
+	var dbChirp database.Chirp
+	err = a.runTx(req.Context(), func(q database.Querier) error {
+		var err error
+		updateArgs := database.UpdateChirpBodyParams{
+			ID:          chirpID,
+			Body:        body.Body,
+			Lang:        lang.Detect(body.Body),
+			IfUpdatedAt: ifUpdatedAt,
+		}
+		dbChirp, err = q.UpdateChirpBody(req.Context(), updateArgs)
+		if err != nil {
+			return err
+		}
+
+		revisionArgs := database.CreateChirpRevisionParams{
+			ChirpID: dbChirp.ID,
+			Body:    dbChirp.Body,
+		}
+		return q.CreateChirpRevision(req.Context(), revisionArgs)
+	})
+	if ifUpdatedAt.Valid && errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusPreconditionFailed, "Chirp was changed since If-Match")
+		return
+	}
+	if err != nil {
+		log.Printf("in handlerEditChirp, unable to update chirp: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	chirps, err := chirpsFromDB(req.Context(), a.dbQueries, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{dbChirp})
+	if err != nil {
+		log.Printf("in handlerEditChirp, unable to load chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps[0])
+}
+
+// parseIfMatch reads an If-Match of a chirp's updated_at, quoted like an
+// ETag or not. An empty header means no precondition.
+func parseIfMatch(header string) (sql.NullTime, error) {
+	if header == "" {
+		return sql.NullTime{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, strings.Trim(header, `"`))
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerGetChirpRevisions, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Get chirp id
+	chirpID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerGetChirpRevisions, could not parse chirp id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//Only the author may see the history
+	chirp, err := a.readQueries.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		log.Printf("in handlerGetChirpRevisions, could not get chirp: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if userID != chirp.UserID {
+		log.Printf("in handlerGetChirpRevisions, user is not the author")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	dbRevisions, err := a.readQueries.GetChirpRevisions(req.Context(), chirpID)
+	if err != nil {
+		log.Printf("in handlerGetChirpRevisions, unable to get revisions: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	revisions := []ChirpRevision{}
+	for _, rev := range dbRevisions {
+		revisions = append(revisions, ChirpRevision{
+			ID:        rev.ID,
+			Body:      rev.Body,
+			CreatedAt: rev.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, revisions)
+}
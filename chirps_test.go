@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+	"github.com/kbm-ky/chirpy/internal/memstore"
+	"github.com/kbm-ky/chirpy/internal/profanity"
+)
+
+func TestCheckChirpLength(t *testing.T) {
+	//no minimum, so only emptiness is being checked
+	a := &apiConfig{minChirpLength: 0, maxChirpLength: defaultMaxChirpLength}
+
+	for _, body := range []string{"", "   ", "\n\t"} {
+		if _, problem := a.checkChirpLength(body, false); problem != "Chirp is empty" {
+			t.Errorf("checkChirpLength(%q) = %q, want it rejected as empty", body, problem)
+		}
+	}
+
+	body, problem := a.checkChirpLength("  hello\n", false)
+	if problem != "" || body != "hello" {
+		t.Errorf("checkChirpLength returned %q, %q; want trimmed body and no problem", body, problem)
+	}
+}
+
+func TestChirpLengthTiers(t *testing.T) {
+	a := &apiConfig{minChirpLength: 1, maxChirpLength: 140, redChirpLength: 280}
+	cases := []struct {
+		length      int
+		isChirpyRed bool
+		problem     string
+	}{
+		{140, false, ""},
+		{141, false, "Chirp is too long, the limit is 140 characters"},
+		{141, true, ""},
+		{280, true, ""},
+		{281, true, "Chirp is too long, the limit is 280 characters"},
+	}
+
+	for _, c := range cases {
+		_, problem := a.checkChirpLength(strings.Repeat("a", c.length), c.isChirpyRed)
+		if problem != c.problem {
+			t.Errorf("checkChirpLength(%d chars, red=%t) = %q, want %q", c.length, c.isChirpyRed, problem, c.problem)
+		}
+	}
+}
+
+func TestWithinWindow(t *testing.T) {
+	posted := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		window time.Duration
+		after  time.Duration
+		want   bool
+	}{
+		{5 * time.Minute, time.Minute, true},
+		{5 * time.Minute, 5 * time.Minute, true},
+		{5 * time.Minute, 6 * time.Minute, false},
+		{0, 24 * time.Hour, true},
+	}
+
+	for _, c := range cases {
+		if got := withinWindow(posted, c.window, posted.Add(c.after)); got != c.want {
+			t.Errorf("withinWindow(%v window, %v later) = %t, want %t", c.window, c.after, got, c.want)
+		}
+	}
+}
+
+// newTestAPI is an apiConfig on an in-memory store with one user, and an
+// access token for them.
+func newTestAPI(t *testing.T) (*apiConfig, *memstore.Store, database.User, string) {
+	t.Helper()
+	store := memstore.New()
+	a := &apiConfig{
+		dbQueries:       store,
+		readQueries:     store,
+		runTx:           store.WithTx,
+		jwtKeys:         auth.NewHMACKeys("secret"),
+		jwtIssuer:       auth.DefaultIssuer,
+		adminToken:      "admin",
+		allowSignups:    true,
+		minChirpLength:  defaultMinChirpLength,
+		maxChirpLength:  defaultMaxChirpLength,
+		profanity:       profanity.Filter{Words: profanity.DefaultWords},
+		chirpLimiter:    unlimited{},
+		redChirpLimiter: unlimited{},
+	}
+
+	user, err := store.CreateUser(context.Background(), database.CreateUserParams{Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	token, err := auth.MakeJWT(user.ID, false, a.jwtKeys, a.jwtIssuer, time.Hour, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	return a, store, user, token
+}
+
+func TestProfanityMode(t *testing.T) {
+	a, _, _, token := newTestAPI(t)
+	postChirp := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(`{"body":"`+body+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.handlerChirps(w, req)
+		return w
+	}
+
+	cases := []struct {
+		mode     profanity.Mode
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{profanity.Censor, "what a kerfuffle", http.StatusCreated, `"body":"what a ****"`},
+		{profanity.Censor, "all good here", http.StatusCreated, `"body":"all good here"`},
+		{profanity.Reject, "what a kerfuffle", http.StatusBadRequest, `"words":["kerfuffle"]`},
+		{profanity.Reject, "all good here", http.StatusCreated, `"body":"all good here"`},
+	}
+
+	for _, c := range cases {
+		a.profanity.Mode = c.mode
+		w := postChirp(c.body)
+		if w.Code != c.wantCode || !strings.Contains(w.Body.String(), c.wantBody) {
+			t.Errorf("mode %d, %q: got %d %s, want %d with %s", c.mode, c.body, w.Code, w.Body, c.wantCode, c.wantBody)
+		}
+	}
+}
+
+func TestValidateFollowsProfanityMode(t *testing.T) {
+	a, _, _, _ := newTestAPI(t)
+	validate := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps/validate", strings.NewReader(`{"body":"`+body+`"}`))
+		w := httptest.NewRecorder()
+		a.handlerValidateChirp(w, req)
+		return w
+	}
+
+	cases := []struct {
+		mode     profanity.Mode
+		body     string
+		wantBody string
+	}{
+		{profanity.Censor, "what a kerfuffle", `{"valid":true,"cleaned_body":"what a ****","errors":[]}`},
+		{profanity.Censor, "all good here", `{"valid":true,"cleaned_body":"all good here","errors":[]}`},
+		{profanity.Reject, "what a kerfuffle", `{"valid":false,"cleaned_body":"what a kerfuffle","errors":["Chirp contains banned words"],"words":["kerfuffle"]}`},
+		{profanity.Reject, "all good here", `{"valid":true,"cleaned_body":"all good here","errors":[]}`},
+	}
+
+	for _, c := range cases {
+		a.profanity.Mode = c.mode
+		w := validate(c.body)
+		if got := strings.TrimSpace(w.Body.String()); w.Code != http.StatusOK || got != c.wantBody {
+			t.Errorf("mode %d, %q: got %d %s, want %s", c.mode, c.body, w.Code, got, c.wantBody)
+		}
+	}
+}
+
+func TestDeleteWindow(t *testing.T) {
+	ctx := context.Background()
+	a, store, user, token := newTestAPI(t)
+
+	//admin deletes go through middlewareAdmin, which does their auth
+	adminDelete := a.middlewareAdmin(http.HandlerFunc(a.handlerAdminDeleteChirp))
+	deleteChirp := func(handler http.Handler, path, bearer string) (int, database.Chirp) {
+		t.Helper()
+		chirp, err := store.CreateChirp(ctx, database.CreateChirpParams{Body: "hello", UserID: user.ID})
+		if err != nil {
+			t.Fatalf("CreateChirp failed: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodDelete, path+chirp.ID.String(), nil)
+		req.SetPathValue("id", chirp.ID.String())
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code, chirp
+	}
+
+	a.deleteWindow = time.Hour
+	if code, _ := deleteChirp(http.HandlerFunc(a.handlerDeleteChirp), "/api/chirps/", token); code != http.StatusNoContent {
+		t.Errorf("delete within the window: got %d, want %d", code, http.StatusNoContent)
+	}
+
+	a.deleteWindow = time.Nanosecond
+	if code, _ := deleteChirp(http.HandlerFunc(a.handlerDeleteChirp), "/api/chirps/", token); code != http.StatusForbidden {
+		t.Errorf("delete past the window: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code, _ := deleteChirp(adminDelete, "/admin/chirps/", a.adminToken); code != http.StatusNoContent {
+		t.Errorf("admin delete past the window: got %d, want %d", code, http.StatusNoContent)
+	}
+	code, chirp := deleteChirp(adminDelete, "/admin/chirps/", token)
+	if code != http.StatusForbidden {
+		t.Errorf("admin delete with a user's token: got %d, want %d", code, http.StatusForbidden)
+	}
+	if _, err := store.GetChirp(ctx, chirp.ID); err != nil {
+		t.Errorf("expected the chirp to survive a non-admin delete, got %v", err)
+	}
+}
+
+func TestChirpRateLimitForRed(t *testing.T) {
+	a, store, user, token := newTestAPI(t)
+	a.chirpLimiter = newMemoryLimiter("chirp", 1, time.Minute)
+	a.redChirpLength = defaultRedChirpLength
+	postChirp := func() int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(`{"body":"hello"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.handlerChirps(w, req)
+		return w.Code
+	}
+
+	if code := postChirp(); code != http.StatusCreated {
+		t.Fatalf("expected the first chirp to be allowed, got %d", code)
+	}
+	if code := postChirp(); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the second chirp to be limited, got %d", code)
+	}
+
+	if _, err := store.UpgradeUserChirpyRed(context.Background(), user.ID); err != nil {
+		t.Fatalf("UpgradeUserChirpyRed failed: %v", err)
+	}
+	if code := postChirp(); code != http.StatusCreated {
+		t.Fatalf("expected Chirpy Red to skip the limit, got %d", code)
+	}
+}
+
+func TestDuplicateChirpWindow(t *testing.T) {
+	a, _, _, token := newTestAPI(t)
+	postChirp := func(body string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(`{"body":"`+body+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.handlerChirps(w, req)
+		return w.Code
+	}
+
+	//off by default
+	if code := postChirp("hello"); code != http.StatusCreated {
+		t.Fatalf("expected the first chirp to be allowed, got %d", code)
+	}
+	if code := postChirp("hello"); code != http.StatusCreated {
+		t.Fatalf("expected duplicates to be allowed by default, got %d", code)
+	}
+
+	a.duplicateWindow = time.Minute
+	if code := postChirp(" hello "); code != http.StatusConflict {
+		t.Fatalf("expected a recent duplicate to conflict, got %d", code)
+	}
+	if code := postChirp("hello again"); code != http.StatusCreated {
+		t.Fatalf("expected a different chirp to be allowed, got %d", code)
+	}
+}
+
+func TestWebhookOnlyGetsPublicChirps(t *testing.T) {
+	a, _, _, token := newTestAPI(t)
+	//no run goroutine, so sent events stay queued
+	a.webhooks = &webhookDispatcher{queue: make(chan []byte, 10)}
+
+	for _, visibility := range []string{"public", "followers", "private"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(`{"body":"hello","visibility":"`+visibility+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.handlerChirps(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: expected the chirp to be posted, got %d %s", visibility, w.Code, w.Body)
+		}
+	}
+
+	if n := len(a.webhooks.queue); n != 1 {
+		t.Fatalf("expected only the public chirp to be sent, got %d events", n)
+	}
+	if body := string(<-a.webhooks.queue); !strings.Contains(body, `"visibility":"public"`) {
+		t.Errorf("expected the public chirp, got %s", body)
+	}
+}
+
+func TestChirpOrderingWithAuthor(t *testing.T) {
+	ctx := context.Background()
+	a, store, user, _ := newTestAPI(t)
+	a.maxChirps = 100
+	other, err := store.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	for i := range 6 {
+		author := user.ID
+		if i%2 == 1 {
+			author = other.ID
+		}
+		args := database.CreateChirpParams{Body: fmt.Sprintf("chirp %d", i), UserID: author}
+		if _, err := store.CreateChirp(ctx, args); err != nil {
+			t.Fatalf("CreateChirp failed: %v", err)
+		}
+	}
+
+	list := func(query string) []Chirp {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps?"+query, nil)
+		w := httptest.NewRecorder()
+		a.handlerGetChirps(w, req)
+		var chirps []Chirp
+		if err := json.Unmarshal(w.Body.Bytes(), &chirps); err != nil {
+			t.Fatalf("%s: unable to decode %s: %v", query, w.Body, err)
+		}
+		return chirps
+	}
+
+	for _, sort := range []string{"asc", "desc"} {
+		var want []uuid.UUID
+		for _, chirp := range list("sort=" + sort) {
+			if chirp.UserID == user.ID {
+				want = append(want, chirp.ID)
+			}
+		}
+		var got []uuid.UUID
+		for _, chirp := range list("sort=" + sort + "&author_id=" + user.ID.String()) {
+			got = append(got, chirp.ID)
+		}
+		if len(want) != 3 || !slices.Equal(got, want) {
+			t.Errorf("sort=%s: author chirps %v, want them in the same order as all chirps %v", sort, got, want)
+		}
+	}
+}
+
+func TestValidateEditAndCreateAgree(t *testing.T) {
+	a, store, user, token := newTestAPI(t)
+	existing, err := store.CreateChirp(context.Background(), database.CreateChirpParams{Body: "original", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %v", err)
+	}
+	send := func(method string, handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, _ := json.Marshal(map[string]string{"body": body})
+		req := httptest.NewRequest(method, "/api/chirps", strings.NewReader(string(payload)))
+		req.SetPathValue("id", existing.ID.String())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	cases := []struct {
+		mode     profanity.Mode
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{profanity.Censor, "  fine as it is  ", 0, `"body":"fine as it is"`},
+		{profanity.Censor, strings.Repeat("a", defaultMaxChirpLength+1), http.StatusBadRequest, "Chirp is too long"},
+		{profanity.Censor, "   ", http.StatusBadRequest, "Chirp is empty"},
+		{profanity.Censor, "what a kerfuffle", 0, `"body":"what a ****"`},
+		{profanity.Reject, "what a kerfuffle", http.StatusBadRequest, `"words":["kerfuffle"]`},
+	}
+	for _, c := range cases {
+		a.profanity.Mode = c.mode
+		created := send(http.MethodPost, a.handlerChirps, c.body)
+		edited := send(http.MethodPut, a.handlerEditChirp, c.body)
+		for name, w := range map[string]*httptest.ResponseRecorder{"create": created, "edit": edited} {
+			if (c.wantCode != 0 && w.Code != c.wantCode) || !strings.Contains(w.Body.String(), c.wantBody) {
+				t.Errorf("%s %q: got %d %s, want %s", name, c.body, w.Code, w.Body, c.wantBody)
+			}
+		}
+		if (created.Code < 300) != (edited.Code < 300) {
+			t.Errorf("%q: create got %d but edit got %d", c.body, created.Code, edited.Code)
+		}
+		var validated struct {
+			Valid bool `json:"valid"`
+		}
+		if err := json.Unmarshal(send(http.MethodPost, a.handlerValidateChirp, c.body).Body.Bytes(), &validated); err != nil {
+			t.Fatalf("unable to decode validate response: %v", err)
+		}
+		if validated.Valid != (created.Code < 300) {
+			t.Errorf("%q: validate said valid=%t but create got %d", c.body, validated.Valid, created.Code)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// trustedProxies are the hops allowed to tell us who the client is through
+// X-Forwarded-For. Anyone else could put anything in it.
+type trustedProxies []netip.Prefix
+
+// trustedProxiesFromEnv reads TRUSTED_PROXIES, a comma-separated list of
+// CIDRs or single addresses, e.g. the load balancer's subnet.
+func trustedProxiesFromEnv() (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, item := range envList("TRUSTED_PROXIES") {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", item, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", item, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func (p trustedProxies) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address of whoever sent req. X-Forwarded-For is only
+// followed back while the hops are trusted proxies: each appends the address
+// it got the request from, so the rightmost untrusted entry is the client.
+// Without trusted proxies that is always RemoteAddr.
+func (p trustedProxies) clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !p.trusts(addr) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			//a proxy we trust wouldn't write garbage, so it came from further out
+			break
+		}
+		addr = hop
+		if !p.trusts(hop) {
+			break
+		}
+	}
+	return addr.Unmap().String()
+}
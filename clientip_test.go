@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+	proxies, err := trustedProxiesFromEnv()
+	if err != nil {
+		t.Fatalf("trustedProxiesFromEnv failed: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"direct", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"untrusted peer can't spoof", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"one proxy", "10.0.0.5:1234", "198.51.100.1", "198.51.100.1"},
+		{"chain of proxies", "10.0.0.5:1234", "198.51.100.1, 192.0.2.1, 10.1.2.3", "198.51.100.1"},
+		{"spoofed entry left of client", "10.0.0.5:1234", "6.6.6.6, 198.51.100.1", "198.51.100.1"},
+		{"proxy without header", "10.0.0.5:1234", "", "10.0.0.5"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remoteAddr
+		if c.xff != "" {
+			req.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if got := proxies.clientIP(req); got != c.want {
+			t.Errorf("%s: clientIP = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
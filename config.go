@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/kbm-ky/chirpy/internal/auth"
+)
+
+// envUint reads an unsigned integer from the environment, falling back to
+// def when the variable is unset.
+func envUint(key string, def uint64, bitSize int) (uint64, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseUint(raw, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// envBool reads a boolean from the environment, falling back to def when
+// the variable is unset.
+func envBool(key string, def bool) (bool, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// envDuration reads a time.ParseDuration value from the environment,
+// falling back to def when the variable is unset.
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be positive", key)
+	}
+	return d, nil
+}
+
+// envWindow reads a time limit from the environment, where unset or 0 means
+// there is none.
+func envWindow(key string) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" || raw == "0" {
+		return 0, nil
+	}
+	return envDuration(key, 0)
+}
+
+// signupsFromEnv reads ALLOW_SIGNUPS: true, the default, or false, or
+// "invite" to only let people sign up with an invite code.
+func signupsFromEnv() (allow, inviteOnly bool, err error) {
+	if os.Getenv("ALLOW_SIGNUPS") == "invite" {
+		return true, true, nil
+	}
+	allow, err = envBool("ALLOW_SIGNUPS", true)
+	return allow, false, err
+}
+
+// envBaseURL reads an absolute http or https URL from the environment,
+// without a trailing slash, for building links. Unset is "".
+func envBaseURL(key string) (string, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid %s: must be an http or https URL like https://chirpy.example.com", key)
+	}
+	return strings.TrimSuffix(raw, "/"), nil
+}
+
+// missingConfig lists the required settings that are unset, so startup can
+// fail with all of them at once. SECRET isn't needed when tokens are signed
+// with an RSA key instead, and the _FILE forms count for the secrets.
+func missingConfig() []string {
+	var missing []string
+	if os.Getenv("PLATFORM") == "" {
+		missing = append(missing, "PLATFORM")
+	}
+	if os.Getenv("DB_URL") == "" && os.Getenv("DB_URL_FILE") == "" {
+		missing = append(missing, "DB_URL")
+	}
+	if os.Getenv("JWT_ALG") != "RS256" && os.Getenv("SECRET") == "" && os.Getenv("SECRET_FILE") == "" {
+		missing = append(missing, "SECRET")
+	}
+	return missing
+}
+
+// envSecret reads a secret from the file named by key+"_FILE" when that is
+// set, as Docker and Kubernetes mount them, and from key itself otherwise.
+// Surrounding whitespace, like the file's trailing newline, is dropped.
+func envSecret(key string) (string, error) {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return os.Getenv(key), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s_FILE: %w", key, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// envList reads a comma-separated list from the environment, skipping
+// blank entries.
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func hashParamsFromEnv() (*argon2id.Params, error) {
+	memory, err := envUint("ARGON2_MEMORY", uint64(argon2id.DefaultParams.Memory), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	iterations, err := envUint("ARGON2_ITERATIONS", uint64(argon2id.DefaultParams.Iterations), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	parallelism, err := envUint("ARGON2_PARALLELISM", uint64(argon2id.DefaultParams.Parallelism), 8)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewHashParams(uint32(memory), uint32(iterations), uint8(parallelism))
+}
+
+// jwtKeysFromEnv picks how access tokens are signed from JWT_ALG: HS256
+// with SECRET by default, or RS256 with the key in JWT_PRIVATE_KEY_FILE,
+// also accepting the keys in JWT_PREVIOUS_PUBLIC_KEY_FILES.
+func jwtKeysFromEnv(secret string) (auth.TokenKeys, error) {
+	switch alg := os.Getenv("JWT_ALG"); alg {
+	case "", "HS256":
+		return auth.NewHMACKeys(secret), nil
+	case "RS256":
+		path := os.Getenv("JWT_PRIVATE_KEY_FILE")
+		if path == "" {
+			return auth.TokenKeys{}, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required with JWT_ALG=RS256")
+		}
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return auth.TokenKeys{}, fmt.Errorf("invalid JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		//public keys of earlier signing keys, still accepted during a rotation
+		var previous [][]byte
+		for _, path := range envList("JWT_PREVIOUS_PUBLIC_KEY_FILES") {
+			publicPEM, err := os.ReadFile(path)
+			if err != nil {
+				return auth.TokenKeys{}, fmt.Errorf("invalid JWT_PREVIOUS_PUBLIC_KEY_FILES: %w", err)
+			}
+			previous = append(previous, publicPEM)
+		}
+		keys, err := auth.NewRSAKeys(pemBytes, previous...)
+		if err != nil {
+			return auth.TokenKeys{}, fmt.Errorf("invalid JWT key: %w", err)
+		}
+		return keys, nil
+	default:
+		return auth.TokenKeys{}, fmt.Errorf("invalid JWT_ALG %q, want HS256 or RS256", alg)
+	}
+}
+
+// logOutputFromEnv picks where log output goes from LOG_FILE: stdout by
+// default, "stderr", or a file path to append to.
+func logOutputFromEnv() (io.Writer, error) {
+	switch path := os.Getenv("LOG_FILE"); path {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	default:
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_FILE: %w", err)
+		}
+		return f, nil
+	}
+}
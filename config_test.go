@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SECRET", "from-env")
+
+	got, err := envSecret("SECRET")
+	if err != nil || got != "from-env" {
+		t.Fatalf("expected the variable without a file, got %q, %v", got, err)
+	}
+
+	t.Setenv("SECRET_FILE", path)
+	got, err = envSecret("SECRET")
+	if err != nil || got != "from-file" {
+		t.Fatalf("expected the file to take precedence, got %q, %v", got, err)
+	}
+
+	t.Setenv("SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := envSecret("SECRET"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestMissingConfig(t *testing.T) {
+	t.Setenv("PLATFORM", "")
+	t.Setenv("DB_URL", "memory")
+	t.Setenv("SECRET", "")
+	t.Setenv("SECRET_FILE", "")
+	t.Setenv("JWT_ALG", "")
+
+	got := strings.Join(missingConfig(), ", ")
+	if got != "PLATFORM, SECRET" {
+		t.Fatalf("expected PLATFORM and SECRET missing, got %q", got)
+	}
+
+	t.Setenv("PLATFORM", "dev")
+	t.Setenv("JWT_ALG", "RS256")
+	if missing := missingConfig(); len(missing) != 0 {
+		t.Fatalf("expected nothing missing with an RSA key, got %v", missing)
+	}
+}
+
+func TestSignupsFromEnv(t *testing.T) {
+	for raw, want := range map[string][2]bool{
+		"":       {true, false},
+		"false":  {false, false},
+		"true":   {true, false},
+		"invite": {true, true},
+	} {
+		t.Setenv("ALLOW_SIGNUPS", raw)
+		allow, inviteOnly, err := signupsFromEnv()
+		if err != nil || allow != want[0] || inviteOnly != want[1] {
+			t.Errorf("%q: got %t, %t, %v, want %t, %t", raw, allow, inviteOnly, err, want[0], want[1])
+		}
+	}
+
+	t.Setenv("ALLOW_SIGNUPS", "sometimes")
+	if _, _, err := signupsFromEnv(); err == nil {
+		t.Error("expected an error for an unknown value")
+	}
+}
+
+func TestEnvBaseURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":                             "",
+		"https://chirpy.example.com":   "https://chirpy.example.com",
+		"https://chirpy.example.com/":  "https://chirpy.example.com",
+		"http://localhost:8080/chirpy": "http://localhost:8080/chirpy",
+	} {
+		t.Setenv("PUBLIC_BASE_URL", raw)
+		if got, err := envBaseURL("PUBLIC_BASE_URL"); err != nil || got != want {
+			t.Errorf("%q: got %q, %v, want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"chirpy.example.com", "ftp://chirpy.example.com", "https://chirpy.example.com/?a=b"} {
+		t.Setenv("PUBLIC_BASE_URL", raw)
+		if _, err := envBaseURL("PUBLIC_BASE_URL"); err == nil {
+			t.Errorf("%q: expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+)
+
+const refreshTokenCookie = "refresh_token"
+
+// refreshTokenTTL matches the expiry CreateRefreshToken stamps in the DB.
+const refreshTokenTTL = 60 * 24 * time.Hour
+
+func setRefreshTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    token,
+		Path:     "/api",
+		MaxAge:   int(refreshTokenTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearRefreshTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    "",
+		Path:     "/api",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// csrfTokenCookie is deliberately readable by scripts, which copy it into
+// csrfTokenHeader. It covers the whole site so the app's pages can see it.
+const (
+	csrfTokenCookie = "csrf_token"
+	csrfTokenHeader = "X-CSRF-Token"
+)
+
+func setCSRFTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfTokenCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(refreshTokenTTL.Seconds()),
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearCSRFTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfTokenCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// getRefreshToken prefers the cookie set by a cookie-mode login and falls
+// back to the bearer token used by non-browser clients.
+func getRefreshToken(req *http.Request) (string, error) {
+	if cookie, err := req.Cookie(refreshTokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	return auth.GetBearerToken(req.Header)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsConfig lets browser clients on other origins call the API. With no
+// allowed origins configured no CORS headers are sent at all. In credentialed
+// mode browsers send cookies along, so only listed origins are let through.
+type corsConfig struct {
+	allowedOrigins   []string
+	exposeHeaders    []string
+	maxAgeSeconds    uint64
+	allowCredentials bool
+}
+
+// defaultExposeHeaders are the response headers the API sets itself, which
+// scripts on other origins can't read unless they are exposed.
+var defaultExposeHeaders = []string{"X-Page-Size", "X-Next-Cursor", "X-Total-Count", "Retry-After"}
+
+func corsFromEnv() (corsConfig, error) {
+	c := corsConfig{allowedOrigins: envList("CORS_ALLOWED_ORIGINS")}
+
+	c.exposeHeaders = envList("CORS_EXPOSE_HEADERS")
+	if c.exposeHeaders == nil {
+		c.exposeHeaders = defaultExposeHeaders
+	}
+
+	//how long browsers may cache a preflight, cutting down on OPTIONS requests
+	maxAge, err := envUint("CORS_MAX_AGE", 600, 32)
+	if err != nil {
+		return corsConfig{}, err
+	}
+	c.maxAgeSeconds = maxAge
+
+	c.allowCredentials, err = envBool("CORS_ALLOW_CREDENTIALS", false)
+	if err != nil {
+		return corsConfig{}, err
+	}
+	//browsers refuse credentials with a wildcard origin anyway
+	if c.allowCredentials && slices.Contains(c.allowedOrigins, "*") {
+		return corsConfig{}, errors.New("CORS_ALLOW_CREDENTIALS needs explicit CORS_ALLOWED_ORIGINS, not *")
+	}
+	return c, nil
+}
+
+func (c corsConfig) allowOrigin(origin string) (string, bool) {
+	if slices.Contains(c.allowedOrigins, "*") {
+		return "*", true
+	}
+	if slices.Contains(c.allowedOrigins, origin) {
+		return origin, true
+	}
+	return "", false
+}
+
+func (c corsConfig) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			allowed, ok := c.allowOrigin(origin)
+			if !ok && c.allowCredentials {
+				respondWithError(w, http.StatusForbidden, "Origin not allowed")
+				return
+			}
+			if !ok {
+				next.ServeHTTP(w, req)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			if c.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			//answer preflights here, handlers never see them
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key, If-Match, X-Client-Type, X-CSRF-Token")
+				w.Header().Set("Access-Control-Max-Age", strconv.FormatUint(c.maxAgeSeconds, 10))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if len(c.exposeHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.exposeHeaders, ", "))
+			}
+			next.ServeHTTP(w, req)
+		})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareCSRF(t *testing.T) {
+	a := &apiConfig{refreshCookie: true}
+	handler := a.middlewareCSRF(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name   string
+		method string
+		bearer bool
+		cookie bool
+		header string
+		want   int
+	}{
+		{"cookie with matching header", http.MethodPost, false, true, "csrf", http.StatusNoContent},
+		{"cookie without header", http.MethodPost, false, true, "", http.StatusForbidden},
+		{"cookie with wrong header", http.MethodDelete, false, true, "other", http.StatusForbidden},
+		{"cookie on a read", http.MethodGet, false, true, "", http.StatusNoContent},
+		{"bearer token", http.MethodPost, true, true, "", http.StatusNoContent},
+		{"no cookie", http.MethodPost, false, false, "", http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/refresh", nil)
+			if tc.cookie {
+				req.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: "refresh"})
+				req.AddCookie(&http.Cookie{Name: csrfTokenCookie, Value: "csrf"})
+			}
+			if tc.bearer {
+				req.Header.Set("Authorization", "Bearer refresh")
+			}
+			if tc.header != "" {
+				req.Header.Set(csrfTokenHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
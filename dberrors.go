@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// isUniqueViolation reports whether err is Postgres SQLSTATE 23505.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isDBTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// respondWithDBError answers a failed query with code, unless the query
+// ran out of time, which is a 503 so clients know to retry later.
+func respondWithDBError(w http.ResponseWriter, code int, err error) {
+	if isDBTimeout(err) {
+		respondWithError(w, http.StatusServiceUnavailable, "Database timed out, try again later")
+		return
+	}
+	w.WriteHeader(code)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// purgeInterval is how often deactivated accounts past the grace period are
+// deleted for good.
+const purgeInterval = time.Hour
+
+// handlerDeactivateUser is the user deleting their own account. It is only
+// hidden and logged out at first, and can be reactivated until the grace
+// period runs out.
+func (a *apiConfig) handlerDeactivateUser(w http.ResponseWriter, req *http.Request) {
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerDeactivateUser, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	err = a.runTx(req.Context(), func(q database.Querier) error {
+		if _, err := q.DeactivateUser(req.Context(), userID); err != nil {
+			return err
+		}
+		_, err := q.RevokeAllRefreshTokensForUser(req.Context(), userID)
+		return err
+	})
+	if err != nil {
+		log.Printf("in handlerDeactivateUser, unable to deactivate user: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("user %s deactivated", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerReactivateUser restores a deactivated account. It takes the same
+// credentials as a login, and the user logs in as usual afterwards.
+func (a *apiConfig) handlerReactivateUser(w http.ResponseWriter, req *http.Request) {
+	type reqBody struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerReactivateUser, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	dbUser, err := a.dbQueries.GetUserByEmail(req.Context(), body.Email)
+	if err != nil {
+		log.Printf("in handlerReactivateUser, unable to find user by email: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
+	match, err := auth.CheckPassword(body.Password, dbUser.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
+	//same answer as a wrong password, so this can't be used to test them
+	if !dbUser.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
+	reactivateArgs := database.ReactivateUserParams{
+		ID:            dbUser.ID,
+		DeactivatedAt: sql.NullTime{Time: time.Now().Add(-a.deletionGrace), Valid: true},
+	}
+	dbUser, err = a.dbQueries.ReactivateUser(req.Context(), reactivateArgs)
+	if errors.Is(err, sql.ErrNoRows) {
+		//the purge just hasn't got to it yet
+		respondWithError(w, http.StatusGone, "Account can no longer be reactivated")
+		return
+	}
+	if err != nil {
+		log.Printf("in handlerReactivateUser, unable to reactivate user: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("user %s reactivated", dbUser.ID)
+	respondWithJSON(w, http.StatusOK, userFromDB(dbUser))
+}
+
+// purgeDeactivatedUsers deletes accounts deactivated longer than the grace
+// period, along with everything of theirs, every purgeInterval. It runs for
+// the life of the server.
+func (a *apiConfig) purgeDeactivatedUsers() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		before := sql.NullTime{Time: time.Now().Add(-a.deletionGrace), Valid: true}
+		n, err := a.dbQueries.PurgeDeactivatedUsers(context.Background(), before)
+		if err != nil {
+			log.Printf("in purgeDeactivatedUsers, unable to purge users: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("purged %d deactivated users", n)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func TestReactivateDoesNotRevealPasswords(t *testing.T) {
+	a, store, _, _ := newTestAPI(t)
+	params, err := auth.NewHashParams(auth.MinHashMemory, 1, 1)
+	if err != nil {
+		t.Fatalf("NewHashParams failed: %v", err)
+	}
+	hash, err := auth.HashPassword("hunter2", params)
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if _, err := store.CreateUser(context.Background(), database.CreateUserParams{Email: "b@example.com", HashedPassword: hash}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	reactivate := func(password string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := `{"email":"b@example.com","password":"` + password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/users/reactivate", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.handlerReactivateUser(w, req)
+		return w
+	}
+
+	right, wrong := reactivate("hunter2"), reactivate("hunter3")
+	if right.Code != http.StatusUnauthorized || right.Code != wrong.Code || right.Body.String() != wrong.Body.String() {
+		t.Errorf("expected an active account to answer alike for any password, got %d %s and %d %s", right.Code, right.Body, wrong.Code, wrong.Body)
+	}
+}
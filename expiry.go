@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// expiryInterval is how often expired chirps are deleted. They are hidden
+// from reads as soon as they expire, this just reclaims the rows.
+const expiryInterval = time.Minute
+
+// deleteExpiredChirps deletes chirps past their expires_at every
+// expiryInterval. It runs for the life of the server.
+func (a *apiConfig) deleteExpiredChirps() {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := a.dbQueries.DeleteExpiredChirps(context.Background())
+		if err != nil {
+			log.Printf("in deleteExpiredChirps, unable to delete chirps: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("deleted %d expired chirps", n)
+		}
+	}
+}
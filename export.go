@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// exportPageSize is how many chirps are read at a time while streaming an
+// export, so a prolific account isn't held in memory all at once.
+const exportPageSize = 100
+
+type ExportedLike struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handlerExport streams everything held about the user as one JSON
+// document: {"user", "chirps", "likes", "follows", "sessions"}. Sessions
+// are listed by id, never by token. Once streaming has started an error
+// can't change the status, so the document is cut short instead, which
+// leaves it invalid rather than silently incomplete.
+func (a *apiConfig) handlerExport(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerExport, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	dbUser, err := a.dbQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerExport, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.json"`)
+	w.WriteHeader(http.StatusOK)
+
+	if err := a.writeExport(req.Context(), w, dbUser); err != nil {
+		log.Printf("in handlerExport, export of user %s cut short: %v", userID, err)
+	}
+}
+
+func (a *apiConfig) writeExport(ctx context.Context, w io.Writer, dbUser database.User) error {
+	enc := json.NewEncoder(w)
+	write := func(s string) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+
+	if err := write(`{"user":`); err != nil {
+		return err
+	}
+	if err := enc.Encode(userFromDB(dbUser)); err != nil {
+		return err
+	}
+
+	//chirps a page at a time, oldest first
+	if err := write(`,"chirps":[`); err != nil {
+		return err
+	}
+	owner := uuid.NullUUID{UUID: dbUser.ID, Valid: true}
+	args := database.ListChirpsParams{
+		AuthorID:   owner,
+		ViewerID:   owner,
+		MaxResults: exportPageSize,
+	}
+	first := true
+	for {
+		dbChirps, err := a.dbQueries.ListChirps(ctx, args)
+		if err != nil {
+			return err
+		}
+		chirps, err := chirpsFromDB(ctx, a.dbQueries, owner, dbChirps)
+		if err != nil {
+			return err
+		}
+		for _, chirp := range chirps {
+			if !first {
+				if err := write(","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if err := enc.Encode(chirp); err != nil {
+				return err
+			}
+		}
+		if len(dbChirps) < exportPageSize {
+			break
+		}
+		last := dbChirps[len(dbChirps)-1]
+		args.SinceCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		args.SinceID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+
+	dbLikes, err := a.dbQueries.GetLikesByUser(ctx, dbUser.ID)
+	if err != nil {
+		return err
+	}
+	likes := []ExportedLike{}
+	for _, like := range dbLikes {
+		likes = append(likes, ExportedLike{ChirpID: like.ChirpID, CreatedAt: like.CreatedAt})
+	}
+	if err := write(`],"likes":`); err != nil {
+		return err
+	}
+	if err := enc.Encode(likes); err != nil {
+		return err
+	}
+
+	dbFollows, err := a.dbQueries.GetFollowing(ctx, dbUser.ID)
+	if err != nil {
+		return err
+	}
+	follows := []ExportedFollow{}
+	for _, follow := range dbFollows {
+		follows = append(follows, ExportedFollow{UserID: follow.FolloweeID, CreatedAt: follow.CreatedAt})
+	}
+	if err := write(`,"follows":`); err != nil {
+		return err
+	}
+	if err := enc.Encode(follows); err != nil {
+		return err
+	}
+
+	records, err := a.dbQueries.GetActiveRefreshTokensForUser(ctx, dbUser.ID)
+	if err != nil {
+		return err
+	}
+	sessions := []Session{}
+	for _, record := range records {
+		sessions = append(sessions, sessionFromDB(record))
+	}
+	if err := write(`,"sessions":`); err != nil {
+		return err
+	}
+	if err := enc.Encode(sessions); err != nil {
+		return err
+	}
+	return write("}\n")
+}
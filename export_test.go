@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	a, store, user, token := newTestAPI(t)
+
+	//more than a page of chirps
+	for i := range exportPageSize + 5 {
+		args := database.CreateChirpParams{Body: fmt.Sprintf("chirp %d", i), UserID: user.ID}
+		if _, err := store.CreateChirp(ctx, args); err != nil {
+			t.Fatalf("CreateChirp failed: %v", err)
+		}
+	}
+	if _, err := store.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "secret-token", UserID: user.ID}); err != nil {
+		t.Fatalf("CreateRefreshToken failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me/export.json", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	a.handlerExport(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret-token") {
+		t.Error("expected the refresh token to be left out")
+	}
+	var export struct {
+		User     User             `json:"user"`
+		Chirps   []Chirp          `json:"chirps"`
+		Likes    []ExportedLike   `json:"likes"`
+		Follows  []ExportedFollow `json:"follows"`
+		Sessions []Session        `json:"sessions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if export.User.ID != user.ID || len(export.Chirps) != exportPageSize+5 || len(export.Sessions) != 1 {
+		t.Errorf("expected the user, all chirps and the session, got %s, %d chirps, %d sessions", export.User.ID, len(export.Chirps), len(export.Sessions))
+	}
+	if export.Likes == nil || export.Follows == nil {
+		t.Error("expected empty likes and follows as arrays")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// featureFlags turns experimental features on and off without a code
+// change, from FEATURE_<NAME> in the environment. Everything that already
+// shipped defaults to on.
+type featureFlags struct {
+	//off by default, it lets anyone probe which emails have accounts
+	Availability bool `json:"availability"`
+	Bookmarks    bool `json:"bookmarks"`
+	Follows      bool `json:"follows"`
+	Likes        bool `json:"likes"`
+	Quotes       bool `json:"quotes"`
+}
+
+func featuresFromEnv() (featureFlags, error) {
+	var f featureFlags
+	var err error
+	if f.Availability, err = envBool("FEATURE_AVAILABILITY", false); err != nil {
+		return featureFlags{}, err
+	}
+	if f.Bookmarks, err = envBool("FEATURE_BOOKMARKS", true); err != nil {
+		return featureFlags{}, err
+	}
+	if f.Follows, err = envBool("FEATURE_FOLLOWS", true); err != nil {
+		return featureFlags{}, err
+	}
+	if f.Likes, err = envBool("FEATURE_LIKES", true); err != nil {
+		return featureFlags{}, err
+	}
+	if f.Quotes, err = envBool("FEATURE_QUOTES", true); err != nil {
+		return featureFlags{}, err
+	}
+	return f, nil
+}
+
+// requireFeature answers 404 for a route whose feature is turned off, as if
+// it didn't exist.
+func requireFeature(enabled bool, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if !enabled {
+				log.Printf("in requireFeature, %s %s is disabled", req.Method, req.URL.Path)
+				respondWithError(w, http.StatusNotFound, "not found")
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+}
+
+func (a *apiConfig) handlerGetFeatures(w http.ResponseWriter, req *http.Request) {
+	respondWithJSON(w, http.StatusOK, a.features)
+}
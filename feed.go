@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// jsonFeedVersion identifies the JSON Feed spec the timeline feed follows.
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	NextURL     string         `json:"next_url,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	ContentText   string           `json:"content_text"`
+	DatePublished time.Time        `json:"date_published"`
+	DateModified  *time.Time       `json:"date_modified,omitempty"`
+	Authors       []JSONFeedAuthor `json:"authors"`
+	Language      string           `json:"language,omitempty"`
+}
+
+// JSONFeedAuthor names a chirp's author by id, as users have no public
+// name.
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// baseURL is where clients reach the server, for the absolute URLs feeds
+// need: PUBLIC_BASE_URL, or on the dev platform the scheme and host the
+// request was made to. Anywhere else the Host header is the client's to
+// choose and a proxy may have terminated TLS, so it is never trusted.
+func (a *apiConfig) baseURL(req *http.Request) (string, bool) {
+	if a.publicBaseURL != "" {
+		return a.publicBaseURL, true
+	}
+	if a.platform != "dev" {
+		return "", false
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host, true
+}
+
+func jsonFeedItem(base string, dbChirp database.Chirp) JSONFeedItem {
+	item := JSONFeedItem{
+		ID:            dbChirp.ID.String(),
+		URL:           base + "/api/chirps/" + dbChirp.ID.String(),
+		ContentText:   dbChirp.Body,
+		DatePublished: dbChirp.CreatedAt,
+		Authors: []JSONFeedAuthor{{
+			Name: dbChirp.UserID.String(),
+			URL:  base + "/api/users/" + dbChirp.UserID.String(),
+		}},
+		Language: dbChirp.Lang,
+	}
+	if !dbChirp.UpdatedAt.Equal(dbChirp.CreatedAt) {
+		item.DateModified = &dbChirp.UpdatedAt
+	}
+	return item
+}
+
+// handlerGetTimelineFeed serves the user's timeline as a JSON Feed, paged
+// like GET /api/me/timeline with next_url pointing at the next page.
+func (a *apiConfig) handlerGetTimelineFeed(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerGetTimelineFeed, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	query := req.URL.Query()
+	dbChirps, next, err := a.timelinePage(req.Context(), userID, query)
+	if errors.Is(err, errInvalidCursor) {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("in handlerGetTimelineFeed, unable to get timeline: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	base, ok := a.baseURL(req)
+	if !ok {
+		log.Printf("in handlerGetTimelineFeed, PUBLIC_BASE_URL is not set")
+		respondWithError(w, http.StatusServiceUnavailable, "Feeds are not configured")
+		return
+	}
+	feed := JSONFeed{
+		Version:     jsonFeedVersion,
+		Title:       "Chirpy timeline",
+		HomePageURL: base + "/app/",
+		FeedURL:     base + req.URL.Path,
+		Items:       []JSONFeedItem{},
+	}
+	if next != "" {
+		nextQuery := url.Values{"cursor": {next}}
+		if limit := query.Get("limit"); limit != "" {
+			nextQuery.Set("limit", limit)
+		}
+		feed.NextURL = feed.FeedURL + "?" + nextQuery.Encode()
+	}
+	for _, dbChirp := range dbChirps {
+		feed.Items = append(feed.Items, jsonFeedItem(base, dbChirp))
+	}
+
+	jsonDat, err := marshalJSON(feed)
+	if err != nil {
+		log.Printf("in handlerGetTimelineFeed, unable to encode feed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/feed+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonDat)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFeedLinksIgnoreHostHeader(t *testing.T) {
+	a, _, _, token := newTestAPI(t)
+	a.pageSizes = pageSizes{def: 50, max: 100}
+	getFeed := func() *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/me/feed.json", nil)
+		req.Host = "evil.example.com"
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.handlerGetTimelineFeed(w, req)
+		return w
+	}
+
+	if w := getFeed(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected no feed without PUBLIC_BASE_URL, got %d %s", w.Code, w.Body)
+	}
+
+	a.platform = "dev"
+	if w := getFeed(); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"feed_url":"http://evil.example.com/api/me/feed.json"`) {
+		t.Errorf("expected dev to use the request's host, got %d %s", w.Code, w.Body)
+	}
+
+	a.publicBaseURL = "https://chirpy.example.com"
+	w := getFeed()
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"feed_url":"https://chirpy.example.com/api/me/feed.json"`) {
+		t.Errorf("expected links from PUBLIC_BASE_URL, got %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "evil.example.com") {
+		t.Errorf("expected the Host header to be ignored, got %s", w.Body)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (a *apiConfig) handlerFollow(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerFollow, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Get followee id
+	followeeID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerFollow, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "Cannot follow yourself")
+		return
+	}
+
+	//Does the followee exist?
+	_, err = a.dbQueries.GetUser(req.Context(), followeeID)
+	if err != nil {
+		log.Printf("in handlerFollow, could not get user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	//Following twice is a no-op
+	followArgs := database.CreateFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	}
+	err = a.dbQueries.CreateFollow(req.Context(), followArgs)
+	if err != nil {
+		log.Printf("in handlerFollow, unable to create follow: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiConfig) handlerUnfollow(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerUnfollow, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Get followee id
+	followeeID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerUnfollow, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	deleteArgs := database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	}
+	err = a.dbQueries.DeleteFollow(req.Context(), deleteArgs)
+	if err != nil {
+		log.Printf("in handlerUnfollow, unable to delete follow: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultGzipMinSize is the smallest response worth compressing, below it
+// the gzip header and CPU cost more than they save.
+const defaultGzipMinSize = 1 << 10
+
+// middlewareGzip compresses responses for clients that accept gzip. The
+// first minSize bytes are held back to decide: anything shorter, like most
+// error bodies, goes out as it is.
+func middlewareGzip(minSize int, next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			//ranges are of the uncompressed body, leave them to the file server
+			if !acceptsGzip(req) || req.Header.Get("Range") != "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize}
+			defer gw.close()
+			next.ServeHTTP(gw, req)
+		})
+}
+
+// acceptsGzip reports whether Accept-Encoding allows gzip.
+func acceptsGzip(req *http.Request) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		//q=0 means "not this one"
+		name, value, _ := strings.Cut(params, "=")
+		if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); strings.TrimSpace(name) == "q" && err == nil && q == 0 {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter buffers a response until it reaches minSize, then sends it
+// compressed. Whatever is still buffered when the handler returns is sent
+// uncompressed by close.
+type gzipWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	started bool
+	gz      *gzip.Writer
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.started {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minSize {
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start sends the headers and what has been buffered so far, compressed or
+// not. A handler that set its own Content-Encoding is never compressed.
+func (g *gzipWriter) start(compress bool) error {
+	g.started = true
+	h := g.Header()
+	if h.Get("Content-Encoding") != "" {
+		compress = false
+	}
+
+	if compress {
+		//sniff before compressing, net/http would sniff the gzip bytes
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(g.buf))
+		}
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.ResponseWriter.WriteHeader(g.status)
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+		_, err := g.gz.Write(g.buf)
+		g.buf = nil
+		return err
+	}
+
+	if g.status != 0 {
+		g.ResponseWriter.WriteHeader(g.status)
+	}
+	var err error
+	if len(g.buf) > 0 {
+		_, err = g.ResponseWriter.Write(g.buf)
+	}
+	g.buf = nil
+	return err
+}
+
+func (g *gzipWriter) close() {
+	if !g.started {
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+	}
+}
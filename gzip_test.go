@@ -0,0 +1,52 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareGzip(t *testing.T) {
+	large := strings.Repeat("chirp-", 500)
+	handler := middlewareGzip(defaultGzipMinSize, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, req.URL.Query().Get("body"))
+	}))
+	get := func(body, acceptEncoding string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/?body="+body, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("small", "gzip")
+	if w.Code != http.StatusTeapot || w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Errorf("expected a small response to pass through, got %d %q %q", w.Code, w.Header().Get("Content-Encoding"), w.Body)
+	}
+
+	w = get(large, "gzip")
+	if w.Code != http.StatusTeapot || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a large response to be compressed, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader failed: %v", err)
+	}
+	got, err := io.ReadAll(gz)
+	if err != nil || string(got) != large {
+		t.Errorf("expected the body back after decompressing, got %d bytes, %v", len(got), err)
+	}
+
+	for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+		w = get(large, acceptEncoding)
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+			t.Errorf("Accept-Encoding %q: expected no compression, got %q", acceptEncoding, w.Header().Get("Content-Encoding"))
+		}
+	}
+}
@@ -2,26 +2,89 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+// Lower bounds for tuned argon2id parameters. The memory floor follows the
+// OWASP recommendation of 19 MiB.
+const (
+	MinHashMemory      = 19 * 1024
+	MinHashIterations  = 1
+	MinHashParallelism = 1
+)
+
+// NewHashParams returns argon2id parameters for HashPassword, keeping the
+// default salt and key lengths.
+func NewHashParams(memory, iterations uint32, parallelism uint8) (*argon2id.Params, error) {
+	if memory < MinHashMemory {
+		return nil, fmt.Errorf("argon2id memory must be at least %d KiB, got %d", MinHashMemory, memory)
+	}
+	if iterations < MinHashIterations {
+		return nil, fmt.Errorf("argon2id iterations must be at least %d, got %d", MinHashIterations, iterations)
+	}
+	if parallelism < MinHashParallelism {
+		return nil, fmt.Errorf("argon2id parallelism must be at least %d, got %d", MinHashParallelism, parallelism)
+	}
+
+	return &argon2id.Params{
+		Memory:      memory,
+		Iterations:  iterations,
+		Parallelism: parallelism,
+		SaltLength:  argon2id.DefaultParams.SaltLength,
+		KeyLength:   argon2id.DefaultParams.KeyLength,
+	}, nil
+}
+
+// HashPassword hashes with the given parameters. They are encoded in the
+// hash itself, so CheckPassword keeps working after they change.
+func HashPassword(password string, params *argon2id.Params) (string, error) {
+	hash, err := argon2id.CreateHash(password, params)
 	if err != nil {
 		return "", err
 	}
 	return hash, nil
 }
 
+// NeedsRehash reports whether hash was made with parameters other than
+// params, or isn't argon2id at all, so it can be upgraded the next time the
+// password is known.
+func NeedsRehash(hash string, params *argon2id.Params) (bool, error) {
+	if isBcrypt(hash) {
+		return true, nil
+	}
+	current, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	return *current != *params, nil
+}
+
+// CheckPassword also accepts bcrypt hashes, as imported from other systems,
+// until NeedsRehash has them replaced.
 func CheckPassword(password, hash string) (bool, error) {
+	if isBcrypt(hash) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	result, err := argon2id.ComparePasswordAndHash(password, hash)
 	if err != nil {
 		return false, err
@@ -29,19 +92,64 @@ func CheckPassword(password, hash string) (bool, error) {
 	return result, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// isBcrypt reports whether hash is in bcrypt's modular crypt format.
+func isBcrypt(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims is what ValidateJWT recovers from a token.
+type Claims struct {
+	UserID      uuid.UUID
+	IsChirpyRed bool
+	Issuer      string
+	Audience    []string
+	ExpiresAt   time.Time
+	IssuedAt    time.Time
+}
+
+// chirpyClaims is the JWT payload. Tokens minted before is_chirpy_red was
+// added simply decode it as false.
+type chirpyClaims struct {
+	IsChirpyRed bool `json:"is_chirpy_red,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ErrTokenExpired is wrapped by ValidateJWT's error for a token that is
+// otherwise valid but past its expiry.
+var ErrTokenExpired = jwt.ErrTokenExpired
+
+// DefaultIssuer is the iss claim used unless an instance configures its own.
+const DefaultIssuer = "chirpy"
+
+// MakeJWT signs an access token. An empty audience leaves the aud claim out,
+// so the token is accepted by every endpoint.
+func MakeJWT(userID uuid.UUID, isChirpyRed bool, keys TokenKeys, issuer string, expiresIn time.Duration, audience string) (string, error) {
 	now := time.Now().UTC()
-	claims := jwt.RegisteredClaims{
-		Issuer: "chirpy",
-		IssuedAt: &jwt.NumericDate{
-			Time: time.Now().UTC(),
+	claims := chirpyClaims{
+		IsChirpyRed: isChirpyRed,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: issuer,
+			IssuedAt: &jwt.NumericDate{
+				Time: time.Now().UTC(),
+			},
+			ExpiresAt: &jwt.NumericDate{Time: now.Add(expiresIn)},
+			Subject:   userID.String(),
 		},
-		ExpiresAt: &jwt.NumericDate{Time: now.Add(expiresIn)},
-		Subject:   userID.String(),
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(tokenSecret))
+	token := jwt.NewWithClaims(keys.method, claims)
+	if keys.kid != "" {
+		token.Header["kid"] = keys.kid
+	}
+	signed, err := token.SignedString(keys.signKey)
 	if err != nil {
 		return "", err
 	}
@@ -49,26 +157,56 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return signed, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
-		return []byte(tokenSecret), nil
-	})
+// ValidateJWT checks the token's signature, expiry and issuer. With a
+// non-empty audience, a token scoped to some other audience is rejected;
+// tokens with no aud claim still pass, as they predate audiences. Only the
+// alg the keys sign with is accepted.
+func ValidateJWT(tokenString string, keys TokenKeys, issuer, audience string) (Claims, error) {
+	claims := chirpyClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, keys.verifyKey,
+		jwt.WithIssuer(issuer), jwt.WithValidMethods([]string{keys.Alg()}))
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
+	}
+
+	if audience != "" && len(claims.Audience) > 0 && !slices.Contains(claims.Audience, audience) {
+		return Claims{}, fmt.Errorf("token is not valid for audience %q", audience)
 	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
 
 	userID, err := uuid.Parse(userIDString)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	result := Claims{
+		UserID:      userID,
+		IsChirpyRed: claims.IsChirpyRed,
+		Issuer:      claims.Issuer,
+		Audience:    claims.Audience,
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Time
+	}
+
+	return result, nil
+}
+
+// ValidateJWTUserID is ValidateJWT for callers that only need the subject.
+func ValidateJWTUserID(tokenString string, keys TokenKeys, issuer, audience string) (uuid.UUID, error) {
+	claims, err := ValidateJWT(tokenString, keys, issuer, audience)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	return userID, nil
+	return claims.UserID, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -122,3 +260,33 @@ func MakeRefreshToken() (string, error) {
 
 	return hex.EncodeToString(data), nil
 }
+
+// HashRefreshToken returns the form of a refresh token stored at rest; only
+// the raw token is ever given to the client.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MakeAPIKey returns a new key for scripts to authenticate with. Like a
+// refresh token, only HashAPIKey of it is stored.
+func MakeAPIKey() (string, error) {
+	return MakeRefreshToken()
+}
+
+// MakeCSRFToken returns a token for double-submit CSRF protection, sent
+// to browsers both as a cookie and, by their scripts, as a header.
+func MakeCSRFToken() (string, error) {
+	return MakeRefreshToken()
+}
+
+// MakeInviteCode returns a code an admin hands out so someone can sign up
+// on an invite-only instance.
+func MakeInviteCode() (string, error) {
+	return MakeRefreshToken()
+}
+
+// HashAPIKey returns the form of an API key stored at rest.
+func HashAPIKey(key string) string {
+	return HashRefreshToken(key)
+}
@@ -1,43 +1,340 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"log"
 	"testing"
 	"time"
 
+	"github.com/alexedwards/argon2id"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var testKeys = NewHMACKeys("foobar")
+
+func TestHashParams(t *testing.T) {
+	cases := []struct {
+		memory, iterations uint32
+		parallelism        uint8
+		ok                 bool
+	}{
+		{64 * 1024, 1, 2, true},
+		{MinHashMemory, MinHashIterations, MinHashParallelism, true},
+		{1024, 1, 2, false},
+		{64 * 1024, 0, 2, false},
+		{64 * 1024, 1, 0, false},
+	}
+
+	for _, c := range cases {
+		_, err := NewHashParams(c.memory, c.iterations, c.parallelism)
+		if (err == nil) != c.ok {
+			t.Errorf("NewHashParams(%d, %d, %d): unexpected err %v", c.memory, c.iterations, c.parallelism, err)
+		}
+	}
+}
+
+func TestHashWithTunedParams(t *testing.T) {
+	params, err := NewHashParams(MinHashMemory, 2, 1)
+	if err != nil {
+		t.Fatalf("NewHashParams failed: %v", err)
+	}
+
+	hash, err := HashPassword("hunter2", params)
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+
+	match, err := CheckPassword("hunter2", hash)
+	if err != nil || !match {
+		t.Fatalf("CheckPassword failed: %v", err)
+	}
+}
+
+func TestBcryptHashes(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %v", err)
+	}
+
+	if match, err := CheckPassword("hunter2", string(hash)); err != nil || !match {
+		t.Errorf("expected the bcrypt hash to match, got %t, %v", match, err)
+	}
+	if match, err := CheckPassword("hunter3", string(hash)); err != nil || match {
+		t.Errorf("expected a wrong password not to match, got %t, %v", match, err)
+	}
+	if rehash, err := NeedsRehash(string(hash), argon2id.DefaultParams); err != nil || !rehash {
+		t.Errorf("expected bcrypt hashes to need a rehash, got %t, %v", rehash, err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak, err := NewHashParams(MinHashMemory, 1, 1)
+	if err != nil {
+		t.Fatalf("NewHashParams failed: %v", err)
+	}
+	strong, err := NewHashParams(MinHashMemory, 2, 1)
+	if err != nil {
+		t.Fatalf("NewHashParams failed: %v", err)
+	}
+
+	hash, err := HashPassword("hunter2", weak)
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+
+	if rehash, err := NeedsRehash(hash, weak); err != nil || rehash {
+		t.Errorf("expected no rehash with the same params, got %t, %v", rehash, err)
+	}
+	if rehash, err := NeedsRehash(hash, strong); err != nil || !rehash {
+		t.Errorf("expected a rehash with stronger params, got %t, %v", rehash, err)
+	}
+}
+
 func TestMakeJwt(t *testing.T) {
 
 	id1 := uuid.New()
-	token, err := MakeJWT(id1, "foobar", time.Duration(1*time.Minute))
+	token, err := MakeJWT(id1, false, testKeys, DefaultIssuer, time.Duration(1*time.Minute), "")
 	if err != nil {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
 
-	id2, err := ValidateJWT(token, "foobar")
+	claims, err := ValidateJWT(token, testKeys, DefaultIssuer, "")
 	if err != nil {
 		t.Fatalf("ValidateJWT failed: %v", err)
 	}
 
-	if id1 != id2 {
-		t.Fatalf("ids not equal, %s != %s", id1, id2)
+	if id1 != claims.UserID {
+		t.Fatalf("ids not equal, %s != %s", id1, claims.UserID)
+	}
+}
+
+func TestClaimTimestamps(t *testing.T) {
+	before := time.Now().Add(-time.Second)
+	token, err := MakeJWT(uuid.New(), false, testKeys, DefaultIssuer, time.Duration(1*time.Hour), "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	claims, err := ValidateJWT(token, testKeys, DefaultIssuer, "")
+	if err != nil {
+		t.Fatalf("ValidateJWT failed: %v", err)
+	}
+
+	if claims.IssuedAt.Before(before) || claims.IssuedAt.After(time.Now()) {
+		t.Fatalf("unexpected issued at: %v", claims.IssuedAt)
+	}
+
+	expiresIn := claims.ExpiresAt.Sub(claims.IssuedAt)
+	if expiresIn < 59*time.Minute || expiresIn > 61*time.Minute {
+		t.Fatalf("unexpected expiry: %v", claims.ExpiresAt)
+	}
+}
+
+func TestChirpyRedClaim(t *testing.T) {
+	token, err := MakeJWT(uuid.New(), true, testKeys, DefaultIssuer, time.Duration(1*time.Minute), "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	claims, err := ValidateJWT(token, testKeys, DefaultIssuer, "")
+	if err != nil {
+		t.Fatalf("ValidateJWT failed: %v", err)
+	}
+
+	if !claims.IsChirpyRed {
+		t.Fatalf("expected is_chirpy_red claim to be true")
+	}
+}
+
+func TestTokenWithoutChirpyRedClaim(t *testing.T) {
+	//tokens minted before the claim existed
+	id1 := uuid.New()
+	old := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		Subject:   id1.String(),
+	})
+	token, err := old.SignedString([]byte("foobar"))
+	if err != nil {
+		t.Fatalf("SignedString failed: %v", err)
+	}
+
+	claims, err := ValidateJWT(token, testKeys, DefaultIssuer, "")
+	if err != nil {
+		t.Fatalf("ValidateJWT failed: %v", err)
+	}
+
+	if claims.UserID != id1 || claims.IsChirpyRed {
+		t.Fatalf("unexpected claims: %+v", claims)
 	}
 }
 
 func TestExpiredToken(t *testing.T) {
 	id1 := uuid.New()
-	token, err := MakeJWT(id1, "foobar", time.Duration(1*time.Second))
+	token, err := MakeJWT(id1, false, testKeys, DefaultIssuer, time.Duration(1*time.Second), "")
 	if err != nil {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
 
 	time.Sleep(2 * time.Second)
 
-	_, err = ValidateJWT(token, "foobar")
+	_, err = ValidateJWT(token, testKeys, DefaultIssuer, "")
 	if err == nil {
 		t.Fatalf("unexpected success")
 	}
 	log.Printf("err reason: %v", err)
 }
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken failed: %v", err)
+	}
+
+	hash := HashRefreshToken(token)
+	if hash == token || len(hash) != 64 {
+		t.Fatalf("unexpected hash: %q", hash)
+	}
+	if HashRefreshToken(token) != hash {
+		t.Fatalf("hash is not deterministic")
+	}
+}
+
+func TestJWTAudience(t *testing.T) {
+	web, err := MakeJWT(uuid.New(), false, testKeys, DefaultIssuer, time.Minute, "web")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(web, testKeys, DefaultIssuer, "web"); err != nil {
+		t.Fatalf("ValidateJWT failed for matching audience: %v", err)
+	}
+	if _, err := ValidateJWT(web, testKeys, DefaultIssuer, ""); err != nil {
+		t.Fatalf("ValidateJWT failed without an audience: %v", err)
+	}
+	if _, err := ValidateJWT(web, testKeys, DefaultIssuer, "mobile"); err == nil {
+		t.Fatalf("expected web token to be rejected for mobile")
+	}
+
+	//tokens from before audiences validate everywhere
+	unscoped, err := MakeJWT(uuid.New(), false, testKeys, DefaultIssuer, time.Minute, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(unscoped, testKeys, DefaultIssuer, "mobile"); err != nil {
+		t.Fatalf("ValidateJWT failed for unscoped token: %v", err)
+	}
+}
+
+func TestJWTIssuer(t *testing.T) {
+	token, err := MakeJWT(uuid.New(), false, testKeys, "chirpy-eu", time.Minute, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(token, testKeys, "chirpy-eu", ""); err != nil {
+		t.Fatalf("ValidateJWT failed for matching issuer: %v", err)
+	}
+	if _, err := ValidateJWT(token, testKeys, DefaultIssuer, ""); err == nil {
+		t.Fatalf("expected token from another issuer to be rejected")
+	}
+}
+
+func testRSAKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+func TestRS256(t *testing.T) {
+	_, privatePEM := testRSAKey(t)
+	keys, err := NewRSAKeys(privatePEM)
+	if err != nil {
+		t.Fatalf("NewRSAKeys failed: %v", err)
+	}
+	if keys.Alg() != "RS256" || len(keys.JWKs()) != 1 {
+		t.Fatalf("unexpected keys: %s, %v", keys.Alg(), keys.JWKs())
+	}
+
+	token, err := MakeJWT(uuid.New(), false, keys, DefaultIssuer, time.Minute, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(token, keys, DefaultIssuer, ""); err != nil {
+		t.Fatalf("ValidateJWT failed: %v", err)
+	}
+
+	//an HS256 token must not pass, whatever it was signed with
+	hs256, err := MakeJWT(uuid.New(), false, testKeys, DefaultIssuer, time.Minute, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(hs256, keys, DefaultIssuer, ""); err == nil {
+		t.Fatalf("expected an HS256 token to be rejected in RS256 mode")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, oldPEM := testRSAKey(t)
+	_, newPEM := testRSAKey(t)
+	oldPublicDER, err := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+	}
+	oldPublicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: oldPublicDER})
+
+	before, err := NewRSAKeys(oldPEM)
+	if err != nil {
+		t.Fatalf("NewRSAKeys failed: %v", err)
+	}
+	token, err := MakeJWT(uuid.New(), false, before, DefaultIssuer, time.Minute, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	after, err := NewRSAKeys(newPEM, oldPublicPEM)
+	if err != nil {
+		t.Fatalf("NewRSAKeys failed: %v", err)
+	}
+	if _, err := ValidateJWT(token, after, DefaultIssuer, ""); err != nil {
+		t.Fatalf("expected a token from the previous key to verify: %v", err)
+	}
+
+	jwks := after.JWKs()
+	if len(jwks) != 2 || jwks[0].Kid == before.kid || jwks[1].Kid != before.kid {
+		t.Fatalf("expected the new key first and the old one after, got %v", jwks)
+	}
+
+	dropped, err := NewRSAKeys(newPEM)
+	if err != nil {
+		t.Fatalf("NewRSAKeys failed: %v", err)
+	}
+	if _, err := ValidateJWT(token, dropped, DefaultIssuer, ""); err == nil {
+		t.Fatalf("expected a token from a dropped key to be rejected")
+	}
+}
+
+func TestExpiredTokenError(t *testing.T) {
+	token, err := MakeJWT(uuid.New(), false, testKeys, DefaultIssuer, -time.Minute, "")
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	if _, err := ValidateJWT(token, testKeys, DefaultIssuer, ""); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v", err)
+	}
+	if _, err := ValidateJWT(token+"x", testKeys, DefaultIssuer, ""); errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected a bad signature not to read as expired, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenKeys are what access tokens are signed and checked with: a shared
+// secret for HS256, or an RSA key pair for RS256 so other services can
+// verify tokens with only the public half. In RS256 mode tokens carry the
+// kid of the key that signed them, and the public keys of earlier signing
+// keys can be kept around so their tokens stay valid through a rotation.
+type TokenKeys struct {
+	method  jwt.SigningMethod
+	signKey any
+	kid     string
+	//by kid, the one entry for HS256 has none
+	verifyKeys map[string]any
+}
+
+func NewHMACKeys(secret string) TokenKeys {
+	return TokenKeys{
+		method:     jwt.SigningMethodHS256,
+		signKey:    []byte(secret),
+		verifyKeys: map[string]any{"": []byte(secret)},
+	}
+}
+
+// NewRSAKeys reads a PEM encoded RSA private key, PKCS #1 or #8, to sign
+// with, and the PEM public keys of any earlier ones that should still verify.
+func NewRSAKeys(privateKeyPEM []byte, previousPublicKeysPEM ...[]byte) (TokenKeys, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return TokenKeys{}, err
+	}
+
+	keys := TokenKeys{
+		method:     jwt.SigningMethodRS256,
+		signKey:    key,
+		kid:        thumbprint(&key.PublicKey),
+		verifyKeys: map[string]any{},
+	}
+	keys.verifyKeys[keys.kid] = &key.PublicKey
+	for i, pemBytes := range previousPublicKeysPEM {
+		previous, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return TokenKeys{}, fmt.Errorf("previous key %d: %w", i+1, err)
+		}
+		keys.verifyKeys[thumbprint(previous)] = previous
+	}
+	return keys, nil
+}
+
+// Alg is the JWT alg the keys sign with.
+func (k TokenKeys) Alg() string {
+	return k.method.Alg()
+}
+
+// JWK is an RSA public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKs are the keys tokens can be verified with, the current signing key
+// first, or none for HS256, where there is nothing that can be shared.
+func (k TokenKeys) JWKs() []JWK {
+	var jwks []JWK
+	for kid, key := range k.verifyKeys {
+		key, ok := key.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		jwk := rsaJWK(key)
+		jwk.Use = "sig"
+		jwk.Alg = k.Alg()
+		jwk.Kid = kid
+		jwks = append(jwks, jwk)
+	}
+	slices.SortFunc(jwks, func(a, b JWK) int {
+		switch {
+		case a.Kid == k.kid:
+			return -1
+		case b.Kid == k.kid:
+			return 1
+		}
+		return strings.Compare(a.Kid, b.Kid)
+	})
+	return jwks
+}
+
+// verifyKey is the jwt.Keyfunc for ValidateJWT. Tokens without a kid predate
+// it and were signed by the current key.
+func (k TokenKeys) verifyKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = k.kid
+	}
+	key, ok := k.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func rsaJWK(key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint of key, used as its kid so it is
+// stable across restarts and the same wherever the key is loaded.
+func thumbprint(key *rsa.PublicKey) string {
+	jwk := rsaJWK(key)
+	//required members only, in lexicographic order and without whitespace
+	canonical := fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.Kty, jwk.N)
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
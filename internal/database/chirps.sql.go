@@ -7,29 +7,47 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+	Visibility    ChirpVisibility
+	Lang          string
+	ExpiresAt     sql.NullTime
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.QuotedChirpID,
+		arg.Visibility,
+		arg.Lang,
+		arg.ExpiresAt,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,17 +55,24 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.QuotedChirpID,
+		&i.Visibility,
+		&i.Lang,
+		&i.ExpiresAt,
 	)
 	return i, err
 }
 
-const deleteAllChirps = `-- name: DeleteAllChirps :exec
+const deleteAllChirps = `-- name: DeleteAllChirps :execrows
 DELETE FROM chirps
 `
 
-func (q *Queries) DeleteAllChirps(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteAllChirps)
-	return err
+func (q *Queries) DeleteAllChirps(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllChirps)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const deleteChirp = `-- name: DeleteChirp :exec
@@ -60,10 +85,23 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteExpiredChirps = `-- name: DeleteExpiredChirps :execrows
+DELETE FROM chirps
+WHERE expires_at IS NOT NULL AND expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredChirps(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredChirps)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at
 FROM chirps
-ORDER BY created_at ASC
+ORDER BY created_at ASC, id ASC
 `
 
 func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
@@ -81,6 +119,10 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.QuotedChirpID,
+			&i.Visibility,
+			&i.Lang,
+			&i.ExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -96,7 +138,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at
 FROM chirps
 WHERE id = $1
 LIMIT 1
@@ -111,15 +153,19 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.QuotedChirpID,
+		&i.Visibility,
+		&i.Lang,
+		&i.ExpiresAt,
 	)
 	return i, err
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at
 FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC
+ORDER BY created_at ASC, id ASC
 `
 
 func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -137,6 +183,232 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.QuotedChirpID,
+			&i.Visibility,
+			&i.Lang,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at
+FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.QuotedChirpID,
+			&i.Visibility,
+			&i.Lang,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.visibility, chirps.lang, chirps.expires_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN follows ON follows.followee_id = chirps.user_id AND follows.follower_id = $1
+WHERE (chirps.user_id = $1 OR follows.follower_id IS NOT NULL)
+  AND users.deactivated_at IS NULL
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+  AND (chirps.user_id = $1 OR chirps.visibility <> 'private')
+  AND ($2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.QuotedChirpID,
+			&i.Visibility,
+			&i.Lang,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserChirpStats = `-- name: GetUserChirpStats :one
+SELECT
+    COUNT(*) AS chirp_count,
+    MIN(created_at)::timestamp AS first_chirp_at,
+    MAX(created_at)::timestamp AS last_chirp_at
+FROM chirps
+WHERE user_id = $1
+  AND visibility = 'public'
+  AND (expires_at IS NULL OR expires_at > NOW())
+GROUP BY user_id
+`
+
+type GetUserChirpStatsRow struct {
+	ChirpCount   int64
+	FirstChirpAt time.Time
+	LastChirpAt  time.Time
+}
+
+func (q *Queries) GetUserChirpStats(ctx context.Context, userID uuid.UUID) (GetUserChirpStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserChirpStats, userID)
+	var i GetUserChirpStatsRow
+	err := row.Scan(&i.ChirpCount, &i.FirstChirpAt, &i.LastChirpAt)
+	return i, err
+}
+
+const hasRecentDuplicateChirp = `-- name: HasRecentDuplicateChirp :one
+SELECT EXISTS (
+    SELECT 1
+    FROM chirps
+    WHERE user_id = $1 AND body = $2 AND created_at > $3
+)
+`
+
+type HasRecentDuplicateChirpParams struct {
+	UserID    uuid.UUID
+	Body      string
+	NotBefore time.Time
+}
+
+func (q *Queries) HasRecentDuplicateChirp(ctx context.Context, arg HasRecentDuplicateChirpParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasRecentDuplicateChirp, arg.UserID, arg.Body, arg.NotBefore)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listChirps = `-- name: ListChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.visibility, chirps.lang, chirps.expires_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE ($1::uuid IS NULL OR chirps.user_id = $1::uuid)
+  AND users.deactivated_at IS NULL
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+  AND (NOT $2::boolean OR users.is_chirpy_red)
+  AND (chirps.visibility = 'public'
+    OR chirps.user_id = $3::uuid
+    OR (chirps.visibility = 'followers' AND EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = $3::uuid AND follows.followee_id = chirps.user_id)))
+  AND ($4::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($4::timestamp, $5::uuid))
+  AND ($6::text IS NULL OR chirps.lang = $6::text)
+ORDER BY
+    CASE WHEN $7::boolean THEN chirps.created_at END DESC,
+    CASE WHEN $7::boolean THEN chirps.id END DESC,
+    chirps.created_at ASC,
+    chirps.id ASC
+LIMIT $8
+`
+
+type ListChirpsParams struct {
+	AuthorID       uuid.NullUUID
+	AuthorRed      bool
+	ViewerID       uuid.NullUUID
+	SinceCreatedAt sql.NullTime
+	SinceID        uuid.NullUUID
+	Lang           sql.NullString
+	NewestFirst    bool
+	MaxResults     int32
+}
+
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps,
+		arg.AuthorID,
+		arg.AuthorRed,
+		arg.ViewerID,
+		arg.SinceCreatedAt,
+		arg.SinceID,
+		arg.Lang,
+		arg.NewestFirst,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.QuotedChirpID,
+			&i.Visibility,
+			&i.Lang,
+			&i.ExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -150,3 +422,40 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET updated_at = NOW(), body = $2, lang = $3
+WHERE id = $1
+  AND ($4::timestamp IS NULL OR updated_at = $4::timestamp)
+RETURNING id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID          uuid.UUID
+	Body        string
+	Lang        string
+	IfUpdatedAt sql.NullTime
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody,
+		arg.ID,
+		arg.Body,
+		arg.Lang,
+		arg.IfUpdatedAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.QuotedChirpID,
+		&i.Visibility,
+		&i.Lang,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
@@ -6,17 +6,126 @@ package database
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type Chirp struct {
+type ChirpVisibility string
+
+const (
+	ChirpVisibilityPublic    ChirpVisibility = "public"
+	ChirpVisibilityFollowers ChirpVisibility = "followers"
+	ChirpVisibilityPrivate   ChirpVisibility = "private"
+)
+
+func (e *ChirpVisibility) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChirpVisibility(s)
+	case string:
+		*e = ChirpVisibility(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChirpVisibility: %T", src)
+	}
+	return nil
+}
+
+type NullChirpVisibility struct {
+	ChirpVisibility ChirpVisibility
+	Valid           bool // Valid is true if ChirpVisibility is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChirpVisibility) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChirpVisibility, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChirpVisibility.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChirpVisibility) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChirpVisibility), nil
+}
+
+type ApiKey struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
+	KeyHash   string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Chirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+	Visibility    ChirpVisibility
+	Lang          string
+	ExpiresAt     sql.NullTime
+}
+
+type ChirpAttachment struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Url      string
+	Type     string
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
 	Body      string
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type IdempotencyKey struct {
 	UserID    uuid.UUID
+	Key       string
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type InviteCode struct {
+	Code      string
+	CreatedAt time.Time
+	ExpiresAt sql.NullTime
+	MaxUses   int32
+	Uses      int32
+}
+
+type InviteCodeUse struct {
+	Code      string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -35,4 +144,7 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	LastLoginAt    sql.NullTime
+	Suspended      bool
+	DeactivatedAt  sql.NullTime
 }
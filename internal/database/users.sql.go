@@ -7,10 +7,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users
+`
+
+func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
@@ -20,7 +35,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
 `
 
 type CreateUserParams struct {
@@ -38,21 +53,114 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const deactivateUser = `-- name: DeactivateUser :one
+UPDATE users
+SET updated_at = NOW(), deactivated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, deactivateUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
 
-const deleteAllUsers = `-- name: DeleteAllUsers :exec
+const deleteAllUsers = `-- name: DeleteAllUsers :execrows
 DELETE FROM users
 `
 
-func (q *Queries) DeleteAllUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteAllUsers)
-	return err
+func (q *Queries) DeleteAllUsers(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllUsers)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const downgradeUserChirpyRed = `-- name: DowngradeUserChirpyRed :one
+UPDATE users
+SET updated_at = NOW(), is_chirpy_red = false
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
+`
+
+func (q *Queries) DowngradeUserChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, downgradeUserChirpyRed, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const emailExists = `-- name: EmailExists :one
+SELECT EXISTS (
+    SELECT 1
+    FROM users
+    WHERE email = $1
+)
+`
+
+func (q *Queries) EmailExists(ctx context.Context, email string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, emailExists, email)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
+FROM users
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
+	)
+	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
 FROM users
 WHERE email = $1
 LIMIT 1
@@ -68,6 +176,175 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT
+    id,
+    created_at,
+    is_chirpy_red,
+    (SELECT COUNT(*) FROM follows WHERE followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = users.id) AS following_count
+FROM users
+WHERE id = ANY($1::uuid[])
+  AND deactivated_at IS NULL
+ORDER BY created_at
+`
+
+type GetUsersByIDsRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	IsChirpyRed    bool
+	FollowerCount  int64
+	FollowingCount int64
+}
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]GetUsersByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByIDsRow
+	for rows.Next() {
+		var i GetUsersByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.IsChirpyRed,
+			&i.FollowerCount,
+			&i.FollowingCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
+FROM users
+ORDER BY created_at, id
+LIMIT $1 OFFSET $2
+`
+
+type ListUsersParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.LastLoginAt,
+			&i.Suspended,
+			&i.DeactivatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeactivatedUsers = `-- name: PurgeDeactivatedUsers :execrows
+DELETE FROM users
+WHERE deactivated_at <= $1
+`
+
+func (q *Queries) PurgeDeactivatedUsers(ctx context.Context, deactivatedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeactivatedUsers, deactivatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reactivateUser = `-- name: ReactivateUser :one
+UPDATE users
+SET updated_at = NOW(), deactivated_at = NULL
+WHERE id = $1 AND deactivated_at > $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
+`
+
+type ReactivateUserParams struct {
+	ID            uuid.UUID
+	DeactivatedAt sql.NullTime
+}
+
+func (q *Queries) ReactivateUser(ctx context.Context, arg ReactivateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, reactivateUser, arg.ID, arg.DeactivatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const setUserSuspended = `-- name: SetUserSuspended :one
+UPDATE users
+SET updated_at = NOW(), suspended = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
+`
+
+type SetUserSuspendedParams struct {
+	ID        uuid.UUID
+	Suspended bool
+}
+
+func (q *Queries) SetUserSuspended(ctx context.Context, arg SetUserSuspendedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserSuspended, arg.ID, arg.Suspended)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
@@ -76,7 +353,7 @@ const updateUserEmailAndPass = `-- name: UpdateUserEmailAndPass :one
 UPDATE users
 SET updated_at = NOW(), email = $2, hashed_password = $3
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
 `
 
 type UpdateUserEmailAndPassParams struct {
@@ -95,15 +372,45 @@ func (q *Queries) UpdateUserEmailAndPass(ctx context.Context, arg UpdateUserEmai
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
 
+const updateUserLastLogin = `-- name: UpdateUserLastLogin :exec
+UPDATE users
+SET last_login_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, updateUserLastLogin, id)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET updated_at = NOW(), hashed_password = $2
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
 const upgradeUserChirpyRed = `-- name: UpgradeUserChirpyRed :one
 UPDATE users
 SET updated_at = NOW(), is_chirpy_red = true
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, last_login_at, suspended, deactivated_at
 `
 
 func (q *Queries) UpgradeUserChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -116,6 +423,9 @@ func (q *Queries) UpgradeUserChirpyRed(ctx context.Context, id uuid.UUID) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.LastLoginAt,
+		&i.Suspended,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,122 @@
+// Package lang guesses the language of a chirp. It is a cheap heuristic,
+// not a real detector: the writing system decides for scripts used by one
+// language, and common words decide between languages written in Latin.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Unknown is returned when there isn't enough to go on.
+const Unknown = ""
+
+// scripts maps writing systems used by essentially one language to its
+// ISO 639-1 code. Han is handled separately since Japanese mixes it with
+// kana.
+var scripts = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// commonWords are frequent short words for Latin-script languages. Words
+// shared between languages count for each of them.
+var commonWords = map[string][]string{
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "ein", "eine", "mit", "für", "auf", "zu", "sehr", "aber", "auch", "wir"},
+	"en": {"the", "and", "is", "are", "was", "of", "to", "in", "it", "you", "that", "this", "with", "for", "have", "not", "my", "what"},
+	"es": {"el", "la", "los", "las", "es", "y", "que", "de", "en", "un", "una", "por", "con", "para", "no", "muy", "pero", "está", "yo"},
+	"fr": {"le", "la", "les", "est", "et", "que", "de", "un", "une", "pour", "avec", "pas", "je", "vous", "nous", "très", "mais", "des", "du"},
+	"it": {"il", "lo", "la", "gli", "è", "e", "che", "di", "un", "una", "per", "con", "non", "molto", "ma", "io", "sono"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "ik", "je", "van", "met", "voor", "op", "zijn", "maar", "dat", "wat"},
+	"pt": {"o", "a", "os", "as", "é", "e", "que", "de", "um", "uma", "para", "com", "não", "muito", "mas", "eu", "você", "está"},
+}
+
+// Detect returns the ISO 639-1 code of the language text looks most like,
+// or Unknown.
+func Detect(text string) string {
+	var letters, latin, han, kana int
+	counts := map[string]int{}
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, s := range scripts {
+				if unicode.Is(s.table, r) {
+					counts[s.code]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return Unknown
+	}
+
+	//go by whichever script most of the letters are in
+	best, bestCount := Unknown, 0
+	for code, n := range counts {
+		if n > bestCount {
+			best, bestCount = code, n
+		}
+	}
+	if kana+han > bestCount {
+		best, bestCount = "zh", kana+han
+		if kana > 0 {
+			best = "ja"
+		}
+	}
+	if latin > bestCount {
+		return detectLatin(text)
+	}
+	return best
+}
+
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	scores := map[string]int{}
+	for _, word := range words {
+		for code, common := range commonWords {
+			for _, c := range common {
+				if word == c {
+					scores[code]++
+					break
+				}
+			}
+		}
+	}
+
+	//a tie means we can't tell
+	best, bestScore, tied := Unknown, 0, false
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = code, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied {
+		return Unknown
+	}
+	return best
+}
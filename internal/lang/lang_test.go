@@ -0,0 +1,30 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := map[string]string{
+		"I think this is the best day of the year":      "en",
+		"Hoy es un día muy bonito para ir a la playa":   "es",
+		"Je ne sais pas pourquoi les gens sont tristes": "fr",
+		"Das ist nicht so schlimm, aber ich bin müde":   "de",
+		"今日はとても良い天気です":                                  "ja",
+		"今天天气很好":                                        "zh",
+		"오늘 날씨가 좋네요":                                    "ko",
+		"Сегодня хорошая погода":                        "ru",
+	}
+
+	for input, want := range cases {
+		if got := Detect(input); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	for _, input := range []string{"", "12345 !!!", "lol", "de la"} {
+		if got := Detect(input); got != Unknown {
+			t.Errorf("Detect(%q) = %q, want Unknown", input, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.ApiKey{}, errForeignKey("api_keys", "user_id")
+	}
+	for _, apiKey := range s.apiKeys {
+		if apiKey.KeyHash == arg.KeyHash {
+			return database.ApiKey{}, errUnique("api_keys", "api_keys_key_hash_key")
+		}
+	}
+
+	apiKey := database.ApiKey{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		KeyHash:   arg.KeyHash,
+		CreatedAt: now(),
+	}
+	s.apiKeys[apiKey.ID] = apiKey
+	return apiKey, nil
+}
+
+func (s *Store) DeleteAPIKey(ctx context.Context, arg database.DeleteAPIKeyParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apiKey, ok := s.apiKeys[arg.ID]
+	if !ok || apiKey.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(s.apiKeys, arg.ID)
+	return 1, nil
+}
+
+func (s *Store) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, apiKey := range s.apiKeys {
+		if apiKey.KeyHash == keyHash {
+			return apiKey, nil
+		}
+	}
+	return database.ApiKey{}, sql.ErrNoRows
+}
+
+func (s *Store) ListAPIKeysForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.ApiKey
+	for _, apiKey := range s.apiKeys {
+		if apiKey.UserID == userID {
+			items = append(items, apiKey)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.ApiKey) int {
+		if n := a.CreatedAt.Compare(b.CreatedAt); n != 0 {
+			return n
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return items, nil
+}
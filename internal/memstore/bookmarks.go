@@ -0,0 +1,64 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return errForeignKey("bookmarks", "user_id")
+	}
+	if _, ok := s.chirps[arg.ChirpID]; !ok {
+		return errForeignKey("bookmarks", "chirp_id")
+	}
+
+	key := bookmarkKey{UserID: arg.UserID, ChirpID: arg.ChirpID}
+	if _, ok := s.bookmarks[key]; ok {
+		return nil
+	}
+	s.bookmarks[key] = database.Bookmark{
+		UserID:    arg.UserID,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (s *Store) DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.bookmarks, bookmarkKey{UserID: arg.UserID, ChirpID: arg.ChirpID})
+	return nil
+}
+
+func (s *Store) GetBookmarkedChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bookmarks []database.Bookmark
+	for _, bookmark := range s.bookmarks {
+		if bookmark.UserID == userID {
+			bookmarks = append(bookmarks, bookmark)
+		}
+	}
+	slices.SortFunc(bookmarks, func(a, b database.Bookmark) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	var items []database.Chirp
+	for _, bookmark := range bookmarks {
+		chirp := s.chirps[bookmark.ChirpID]
+		if s.visibleTo(chirp, uuid.NullUUID{UUID: userID, Valid: true}) {
+			items = append(items, chirp)
+		}
+	}
+	return items, nil
+}
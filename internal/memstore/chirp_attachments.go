@@ -0,0 +1,51 @@
+package memstore
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateChirpAttachment(ctx context.Context, arg database.CreateChirpAttachmentParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.chirps[arg.ChirpID]; !ok {
+		return errForeignKey("chirp_attachments", "chirp_id")
+	}
+	key := attachmentKey{ChirpID: arg.ChirpID, Position: arg.Position}
+	if _, ok := s.attachments[key]; ok {
+		return errUnique("chirp_attachments", "chirp_attachments_pkey")
+	}
+
+	s.attachments[key] = database.ChirpAttachment{
+		ChirpID:  arg.ChirpID,
+		Position: arg.Position,
+		Url:      arg.Url,
+		Type:     arg.Type,
+	}
+	return nil
+}
+
+func (s *Store) GetChirpAttachments(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpAttachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.ChirpAttachment
+	for _, attachment := range s.attachments {
+		if slices.Contains(chirpIds, attachment.ChirpID) {
+			items = append(items, attachment)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.ChirpAttachment) int {
+		if c := bytes.Compare(a.ChirpID[:], b.ChirpID[:]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Position, b.Position)
+	})
+	return items, nil
+}
@@ -0,0 +1,43 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateChirpRevision(ctx context.Context, arg database.CreateChirpRevisionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.chirps[arg.ChirpID]; !ok {
+		return errForeignKey("chirp_revisions", "chirp_id")
+	}
+
+	rev := database.ChirpRevision{
+		ID:        uuid.New(),
+		ChirpID:   arg.ChirpID,
+		Body:      arg.Body,
+		CreatedAt: now(),
+	}
+	s.chirpRevisions[rev.ID] = rev
+	return nil
+}
+
+func (s *Store) GetChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.ChirpRevision
+	for _, rev := range s.chirpRevisions {
+		if rev.ChirpID == chirpID {
+			items = append(items, rev)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.ChirpRevision) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items, nil
+}
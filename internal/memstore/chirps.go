@@ -0,0 +1,292 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.Chirp{}, errForeignKey("chirps", "user_id")
+	}
+
+	t := now()
+	chirp := database.Chirp{
+		ID:            uuid.New(),
+		CreatedAt:     t,
+		UpdatedAt:     t,
+		Body:          arg.Body,
+		UserID:        arg.UserID,
+		QuotedChirpID: arg.QuotedChirpID,
+		Visibility:    arg.Visibility,
+		Lang:          arg.Lang,
+		ExpiresAt:     arg.ExpiresAt,
+	}
+	//the column default
+	if chirp.Visibility == "" {
+		chirp.Visibility = database.ChirpVisibilityPublic
+	}
+	s.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (s *Store) DeleteAllChirps(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(len(s.chirps))
+	clear(s.chirps)
+	clear(s.chirpRevisions)
+	clear(s.attachments)
+	clear(s.bookmarks)
+	clear(s.likes)
+	clear(s.idempotency)
+	return n, nil
+}
+
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteChirp(id)
+	return nil
+}
+
+func (s *Store) DeleteExpiredChirps(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, chirp := range s.chirps {
+		if expired(chirp) {
+			s.deleteChirp(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.filterChirps(func(database.Chirp) bool { return true }), nil
+}
+
+func (s *Store) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (s *Store) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.filterChirps(func(c database.Chirp) bool { return c.UserID == userID }), nil
+}
+
+func (s *Store) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.Chirp
+	for _, id := range ids {
+		if chirp, ok := s.chirps[id]; ok {
+			items = append(items, chirp)
+		}
+	}
+	return items, nil
+}
+
+func (s *Store) GetTimeline(ctx context.Context, arg database.GetTimelineParams) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirps := s.filterChirps(func(c database.Chirp) bool {
+		if s.users[c.UserID].DeactivatedAt.Valid || expired(c) {
+			return false
+		}
+		if c.UserID != arg.UserID {
+			_, follows := s.follows[followKey{FollowerID: arg.UserID, FolloweeID: c.UserID}]
+			if !follows || c.Visibility == database.ChirpVisibilityPrivate {
+				return false
+			}
+		}
+		if arg.BeforeCreatedAt.Valid {
+			return compareChirps(c, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID) < 0
+		}
+		return true
+	})
+	slices.Reverse(chirps)
+	return limit(chirps, arg.PageLimit), nil
+}
+
+// GetUserChirpStats reports sql.ErrNoRows for a user with no public
+// chirps, like the GROUP BY in the query.
+func (s *Store) GetUserChirpStats(ctx context.Context, userID uuid.UUID) (database.GetUserChirpStatsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.filterChirps(func(c database.Chirp) bool {
+		return c.UserID == userID && c.Visibility == database.ChirpVisibilityPublic && !expired(c)
+	})
+	if len(items) == 0 {
+		return database.GetUserChirpStatsRow{}, sql.ErrNoRows
+	}
+	return database.GetUserChirpStatsRow{
+		ChirpCount:   int64(len(items)),
+		FirstChirpAt: items[0].CreatedAt,
+		LastChirpAt:  items[len(items)-1].CreatedAt,
+	}, nil
+}
+
+func (s *Store) HasRecentDuplicateChirp(ctx context.Context, arg database.HasRecentDuplicateChirpParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, chirp := range s.chirps {
+		if chirp.UserID == arg.UserID && chirp.Body == arg.Body && chirp.CreatedAt.After(arg.NotBefore) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) ListChirps(ctx context.Context, arg database.ListChirpsParams) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.filterChirps(func(c database.Chirp) bool {
+		if arg.AuthorID.Valid && c.UserID != arg.AuthorID.UUID {
+			return false
+		}
+		if arg.AuthorRed && !s.users[c.UserID].IsChirpyRed {
+			return false
+		}
+		if arg.SinceCreatedAt.Valid && compareChirps(c, arg.SinceCreatedAt.Time, arg.SinceID.UUID) <= 0 {
+			return false
+		}
+		if arg.Lang.Valid && c.Lang != arg.Lang.String {
+			return false
+		}
+		return s.visibleTo(c, arg.ViewerID)
+	})
+	if arg.NewestFirst {
+		slices.Reverse(items)
+	}
+	return limit(items, arg.MaxResults), nil
+}
+
+func (s *Store) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[arg.ID]
+	if !ok || (arg.IfUpdatedAt.Valid && !chirp.UpdatedAt.Equal(arg.IfUpdatedAt.Time)) {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	chirp.UpdatedAt = now()
+	chirp.Body = arg.Body
+	chirp.Lang = arg.Lang
+	s.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+// filterChirps returns the matching chirps ordered by (created_at, id).
+// The caller must hold s.mu.
+func (s *Store) filterChirps(keep func(database.Chirp) bool) []database.Chirp {
+	var items []database.Chirp
+	for _, chirp := range s.chirps {
+		if keep(chirp) {
+			items = append(items, chirp)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.Chirp) int {
+		return compareChirps(a, b.CreatedAt, b.ID)
+	})
+	return items
+}
+
+// deleteChirp removes a chirp and the rows that cascade from it. The caller
+// must hold s.mu.
+func (s *Store) deleteChirp(id uuid.UUID) {
+	delete(s.chirps, id)
+	for revID, rev := range s.chirpRevisions {
+		if rev.ChirpID == id {
+			delete(s.chirpRevisions, revID)
+		}
+	}
+	for key := range s.attachments {
+		if key.ChirpID == id {
+			delete(s.attachments, key)
+		}
+	}
+	for key := range s.bookmarks {
+		if key.ChirpID == id {
+			delete(s.bookmarks, key)
+		}
+	}
+	for key := range s.likes {
+		if key.ChirpID == id {
+			delete(s.likes, key)
+		}
+	}
+	for key, row := range s.idempotency {
+		if row.ChirpID == id {
+			delete(s.idempotency, key)
+		}
+	}
+}
+
+// visibleTo reports whether viewer may see c, following the visibility,
+// deactivation and expiry checks in the queries. The caller must hold s.mu.
+func (s *Store) visibleTo(c database.Chirp, viewer uuid.NullUUID) bool {
+	switch {
+	case s.users[c.UserID].DeactivatedAt.Valid, expired(c):
+		return false
+	case c.Visibility == database.ChirpVisibilityPublic:
+		return true
+	case !viewer.Valid:
+		return false
+	case c.UserID == viewer.UUID:
+		return true
+	case c.Visibility == database.ChirpVisibilityFollowers:
+		_, follows := s.follows[followKey{FollowerID: viewer.UUID, FolloweeID: c.UserID}]
+		return follows
+	}
+	return false
+}
+
+func expired(c database.Chirp) bool {
+	return c.ExpiresAt.Valid && !c.ExpiresAt.Time.After(now())
+}
+
+func compareChirps(c database.Chirp, createdAt time.Time, id uuid.UUID) int {
+	if n := c.CreatedAt.Compare(createdAt); n != 0 {
+		return n
+	}
+	return bytes.Compare(c.ID[:], id[:])
+}
+
+func limit[T any](items []T, n int32) []T {
+	if int(n) < len(items) {
+		return items[:n]
+	}
+	return items
+}
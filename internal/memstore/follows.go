@@ -0,0 +1,93 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CountFollowers(ctx context.Context, followeeID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.follows {
+		if key.FolloweeID == followeeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CountFollowing(ctx context.Context, followerID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.follows {
+		if key.FollowerID == followerID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateFollow(ctx context.Context, arg database.CreateFollowParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.FollowerID == arg.FolloweeID {
+		return errCheck("follows", "follows_check")
+	}
+	if _, ok := s.users[arg.FollowerID]; !ok {
+		return errForeignKey("follows", "follower_id")
+	}
+	if _, ok := s.users[arg.FolloweeID]; !ok {
+		return errForeignKey("follows", "followee_id")
+	}
+
+	key := followKey(arg)
+	if _, ok := s.follows[key]; ok {
+		return nil
+	}
+	s.follows[key] = database.Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  now(),
+	}
+	return nil
+}
+
+func (s *Store) DeleteFollow(ctx context.Context, arg database.DeleteFollowParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.follows, followKey(arg))
+	return nil
+}
+
+func (s *Store) GetFollowing(ctx context.Context, followerID uuid.UUID) ([]database.Follow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.Follow
+	for key, follow := range s.follows {
+		if key.FollowerID == followerID {
+			items = append(items, follow)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.Follow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items, nil
+}
+
+func (s *Store) IsFollowing(ctx context.Context, arg database.IsFollowingParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.follows[followKey(arg)]
+	return ok, nil
+}
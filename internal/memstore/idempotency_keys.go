@@ -0,0 +1,40 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateIdempotencyKey(ctx context.Context, arg database.CreateIdempotencyKeyParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return errForeignKey("idempotency_keys", "user_id")
+	}
+	if _, ok := s.chirps[arg.ChirpID]; !ok {
+		return errForeignKey("idempotency_keys", "chirp_id")
+	}
+
+	key := idempotencyKey{UserID: arg.UserID, Key: arg.Key}
+	s.idempotency[key] = database.IdempotencyKey{
+		UserID:    arg.UserID,
+		Key:       arg.Key,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (s *Store) GetIdempotentChirp(ctx context.Context, arg database.GetIdempotentChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row, ok := s.idempotency[idempotencyKey{UserID: arg.UserID, Key: arg.Key}]
+	if !ok || !row.CreatedAt.After(arg.NotBefore) {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return s.chirps[row.ChirpID], nil
+}
@@ -0,0 +1,64 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateInviteCode(ctx context.Context, arg database.CreateInviteCodeParams) (database.InviteCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.inviteCodes[arg.Code]; ok {
+		return database.InviteCode{}, errUnique("invite_codes", "invite_codes_pkey")
+	}
+
+	invite := database.InviteCode{
+		Code:      arg.Code,
+		CreatedAt: now(),
+		ExpiresAt: arg.ExpiresAt,
+		MaxUses:   arg.MaxUses,
+	}
+	s.inviteCodes[invite.Code] = invite
+	return invite, nil
+}
+
+func (s *Store) CreateInviteCodeUse(ctx context.Context, arg database.CreateInviteCodeUseParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.inviteCodes[arg.Code]; !ok {
+		return errForeignKey("invite_code_uses", "code")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return errForeignKey("invite_code_uses", "user_id")
+	}
+
+	key := inviteCodeUseKey{Code: arg.Code, UserID: arg.UserID}
+	if _, ok := s.inviteCodeUses[key]; ok {
+		return errUnique("invite_code_uses", "invite_code_uses_pkey")
+	}
+	s.inviteCodeUses[key] = database.InviteCodeUse{
+		Code:      arg.Code,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+// UseInviteCode reports sql.ErrNoRows for a code that doesn't exist, has
+// expired or is used up, like the WHERE in the query.
+func (s *Store) UseInviteCode(ctx context.Context, code string) (database.InviteCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.inviteCodes[code]
+	if !ok || invite.Uses >= invite.MaxUses || (invite.ExpiresAt.Valid && !invite.ExpiresAt.Time.After(now())) {
+		return database.InviteCode{}, sql.ErrNoRows
+	}
+	invite.Uses++
+	s.inviteCodes[code] = invite
+	return invite, nil
+}
@@ -0,0 +1,116 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CountLikes(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.likes {
+		if key.ChirpID == chirpID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountLikesReceived only counts likes of the user's public, unexpired
+// chirps, like the query.
+func (s *Store) CountLikesReceived(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.likes {
+		c, ok := s.chirps[key.ChirpID]
+		if ok && c.UserID == userID && c.Visibility == database.ChirpVisibilityPublic && !expired(c) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateLike(ctx context.Context, arg database.CreateLikeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return errForeignKey("likes", "user_id")
+	}
+	if _, ok := s.chirps[arg.ChirpID]; !ok {
+		return errForeignKey("likes", "chirp_id")
+	}
+
+	key := likeKey{UserID: arg.UserID, ChirpID: arg.ChirpID}
+	if _, ok := s.likes[key]; ok {
+		return nil
+	}
+	s.likes[key] = database.Like{
+		UserID:    arg.UserID,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (s *Store) DeleteLike(ctx context.Context, arg database.DeleteLikeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.likes, likeKey{UserID: arg.UserID, ChirpID: arg.ChirpID})
+	return nil
+}
+
+func (s *Store) GetLikedChirps(ctx context.Context, arg database.GetLikedChirpsParams) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var likes []database.Like
+	for _, like := range s.likes {
+		if like.UserID == arg.UserID {
+			likes = append(likes, like)
+		}
+	}
+	slices.SortFunc(likes, func(a, b database.Like) int {
+		if n := b.CreatedAt.Compare(a.CreatedAt); n != 0 {
+			return n
+		}
+		return bytes.Compare(a.ChirpID[:], b.ChirpID[:])
+	})
+
+	var items []database.Chirp
+	for _, like := range likes {
+		chirp := s.chirps[like.ChirpID]
+		if s.visibleTo(chirp, arg.ViewerID) {
+			items = append(items, chirp)
+		}
+	}
+	if int(arg.PageOffset) >= len(items) {
+		return nil, nil
+	}
+	return limit(items[arg.PageOffset:], arg.PageLimit), nil
+}
+
+func (s *Store) GetLikesByUser(ctx context.Context, userID uuid.UUID) ([]database.Like, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.Like
+	for _, like := range s.likes {
+		if like.UserID == userID {
+			items = append(items, like)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.Like) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items, nil
+}
@@ -0,0 +1,96 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func (s *Store) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.RefreshToken{}, errForeignKey("refresh_tokens", "user_id")
+	}
+	if _, ok := s.refreshTokens[arg.Token]; ok {
+		return database.RefreshToken{}, errUnique("refresh_tokens", "refresh_tokens_pkey")
+	}
+
+	t := now()
+	token := database.RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: t,
+		UpdatedAt: t,
+		UserID:    arg.UserID,
+		ExpiresAt: t.Add(60 * 24 * time.Hour),
+	}
+	s.refreshTokens[token.Token] = token
+	return token, nil
+}
+
+func (s *Store) GetActiveRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	var items []database.RefreshToken
+	for _, record := range s.refreshTokens {
+		if record.UserID == userID && !record.RevokedAt.Valid && record.ExpiresAt.After(t) {
+			items = append(items, record)
+		}
+	}
+	slices.SortFunc(items, func(a, b database.RefreshToken) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return items, nil
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.refreshTokens[token]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	return record, nil
+}
+
+func (s *Store) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	t := now()
+	for token, record := range s.refreshTokens {
+		if record.UserID != userID || record.RevokedAt.Valid {
+			continue
+		}
+		record.UpdatedAt = t
+		record.RevokedAt = sql.NullTime{Time: t, Valid: true}
+		s.refreshTokens[token] = record
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.refreshTokens[token]
+	if !ok {
+		return nil
+	}
+
+	t := now()
+	record.UpdatedAt = t
+	record.RevokedAt = sql.NullTime{Time: t, Valid: true}
+	s.refreshTokens[token] = record
+	return nil
+}
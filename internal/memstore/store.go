@@ -0,0 +1,166 @@
+// Package memstore is an in-memory implementation of database.Querier for
+// demos and tests. Nothing is persisted between runs.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+	"github.com/lib/pq"
+)
+
+var _ database.Querier = (*Store)(nil)
+
+type bookmarkKey struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+type likeKey struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+type attachmentKey struct {
+	ChirpID  uuid.UUID
+	Position int32
+}
+
+type idempotencyKey struct {
+	UserID uuid.UUID
+	Key    string
+}
+
+type followKey struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+type inviteCodeUseKey struct {
+	Code   string
+	UserID uuid.UUID
+}
+
+type Store struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+	tables
+}
+
+// tables holds every row in the store, so a transaction can snapshot and
+// restore it as a whole.
+type tables struct {
+	users          map[uuid.UUID]database.User
+	chirps         map[uuid.UUID]database.Chirp
+	chirpRevisions map[uuid.UUID]database.ChirpRevision
+	attachments    map[attachmentKey]database.ChirpAttachment
+	refreshTokens  map[string]database.RefreshToken
+	apiKeys        map[uuid.UUID]database.ApiKey
+	bookmarks      map[bookmarkKey]database.Bookmark
+	likes          map[likeKey]database.Like
+	follows        map[followKey]database.Follow
+	idempotency    map[idempotencyKey]database.IdempotencyKey
+	inviteCodes    map[string]database.InviteCode
+	inviteCodeUses map[inviteCodeUseKey]database.InviteCodeUse
+}
+
+func New() *Store {
+	return &Store{
+		tables: tables{
+			users:          map[uuid.UUID]database.User{},
+			chirps:         map[uuid.UUID]database.Chirp{},
+			chirpRevisions: map[uuid.UUID]database.ChirpRevision{},
+			attachments:    map[attachmentKey]database.ChirpAttachment{},
+			refreshTokens:  map[string]database.RefreshToken{},
+			apiKeys:        map[uuid.UUID]database.ApiKey{},
+			bookmarks:      map[bookmarkKey]database.Bookmark{},
+			likes:          map[likeKey]database.Like{},
+			follows:        map[followKey]database.Follow{},
+			idempotency:    map[idempotencyKey]database.IdempotencyKey{},
+			inviteCodes:    map[string]database.InviteCode{},
+			inviteCodeUses: map[inviteCodeUseKey]database.InviteCodeUse{},
+		},
+	}
+}
+
+func (t tables) clone() tables {
+	return tables{
+		users:          maps.Clone(t.users),
+		chirps:         maps.Clone(t.chirps),
+		chirpRevisions: maps.Clone(t.chirpRevisions),
+		attachments:    maps.Clone(t.attachments),
+		refreshTokens:  maps.Clone(t.refreshTokens),
+		apiKeys:        maps.Clone(t.apiKeys),
+		bookmarks:      maps.Clone(t.bookmarks),
+		likes:          maps.Clone(t.likes),
+		follows:        maps.Clone(t.follows),
+		idempotency:    maps.Clone(t.idempotency),
+		inviteCodes:    maps.Clone(t.inviteCodes),
+		inviteCodeUses: maps.Clone(t.inviteCodeUses),
+	}
+}
+
+// PingContext always succeeds, the store is never unreachable.
+func (s *Store) PingContext(ctx context.Context) error {
+	return nil
+}
+
+// WithTx runs fn against the store, undoing all of its writes if it returns
+// an error. Transactions are serialized with each other but not with
+// writes made outside of one, which is good enough for demos and tests.
+func (s *Store) WithTx(ctx context.Context, fn func(database.Querier) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.tables.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.tables = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// now mirrors Postgres NOW() for TIMESTAMP columns.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// Missing rows are reported as sql.ErrNoRows, as QueryRow does, and
+// constraint violations as *pq.Error with the matching SQLSTATE code, so
+// handlers can treat both backends alike.
+func errForeignKey(table, column string) error {
+	return &pq.Error{
+		Code:    "23503",
+		Message: fmt.Sprintf("insert or update on table %q violates foreign key constraint on %q", table, column),
+		Table:   table,
+		Column:  column,
+	}
+}
+
+func errUnique(table, constraint string) error {
+	return &pq.Error{
+		Code:       "23505",
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func errCheck(table, constraint string) error {
+	return &pq.Error{
+		Code:       "23514",
+		Message:    fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
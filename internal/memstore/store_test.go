@@ -0,0 +1,311 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+	"github.com/lib/pq"
+)
+
+func mustCreateUser(t *testing.T, s *Store, email string) database.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          email,
+		HashedPassword: "unset",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return user
+}
+
+func mustCreateChirp(t *testing.T, s *Store, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	chirp, err := s.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:   body,
+		UserID: userID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %v", err)
+	}
+	return chirp
+}
+
+func TestDuplicateEmail(t *testing.T) {
+	s := New()
+	mustCreateUser(t, s, "a@example.com")
+
+	_, err := s.CreateUser(context.Background(), database.CreateUserParams{Email: "a@example.com"})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		t.Fatalf("expected unique violation, got %v", err)
+	}
+}
+
+func TestGetChirpMissing(t *testing.T) {
+	s := New()
+	_, err := s.GetChirp(context.Background(), uuid.New())
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestDeleteAllUsersCascades(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "a@example.com")
+	mustCreateChirp(t, s, user.ID, "hello")
+
+	n, err := s.DeleteAllUsers(ctx)
+	if err != nil {
+		t.Fatalf("DeleteAllUsers failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 user deleted, got %d", n)
+	}
+
+	chirps, err := s.GetAllChirps(ctx)
+	if err != nil {
+		t.Fatalf("GetAllChirps failed: %v", err)
+	}
+	if len(chirps) != 0 {
+		t.Fatalf("expected no chirps, got %d", len(chirps))
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	me := mustCreateUser(t, s, "me@example.com")
+	friend := mustCreateUser(t, s, "friend@example.com")
+	stranger := mustCreateUser(t, s, "stranger@example.com")
+
+	err := s.CreateFollow(ctx, database.CreateFollowParams{FollowerID: me.ID, FolloweeID: friend.ID})
+	if err != nil {
+		t.Fatalf("CreateFollow failed: %v", err)
+	}
+
+	first := mustCreateChirp(t, s, me.ID, "mine")
+	second := mustCreateChirp(t, s, friend.ID, "theirs")
+	mustCreateChirp(t, s, stranger.ID, "hidden")
+
+	page, err := s.GetTimeline(ctx, database.GetTimelineParams{UserID: me.ID, PageLimit: 1})
+	if err != nil {
+		t.Fatalf("GetTimeline failed: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != second.ID {
+		t.Fatalf("expected newest chirp first, got %v", page)
+	}
+
+	page, err = s.GetTimeline(ctx, database.GetTimelineParams{
+		UserID:          me.ID,
+		BeforeCreatedAt: sql.NullTime{Time: second.CreatedAt, Valid: true},
+		BeforeID:        uuid.NullUUID{UUID: second.ID, Valid: true},
+		PageLimit:       10,
+	})
+	if err != nil {
+		t.Fatalf("GetTimeline failed: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != first.ID {
+		t.Fatalf("expected only the older chirp, got %v", page)
+	}
+}
+
+func TestChirpAttachments(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "a@example.com")
+	chirp := mustCreateChirp(t, s, user.ID, "pics")
+
+	for _, position := range []int32{1, 0} {
+		err := s.CreateChirpAttachment(ctx, database.CreateChirpAttachmentParams{
+			ChirpID:  chirp.ID,
+			Position: position,
+			Url:      "https://example.com/a.png",
+			Type:     "image/png",
+		})
+		if err != nil {
+			t.Fatalf("CreateChirpAttachment failed: %v", err)
+		}
+	}
+
+	attachments, err := s.GetChirpAttachments(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		t.Fatalf("GetChirpAttachments failed: %v", err)
+	}
+	if len(attachments) != 2 || attachments[0].Position != 0 {
+		t.Fatalf("expected attachments in position order, got %v", attachments)
+	}
+
+	if err := s.DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("DeleteChirp failed: %v", err)
+	}
+	attachments, err = s.GetChirpAttachments(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		t.Fatalf("GetChirpAttachments failed: %v", err)
+	}
+	if len(attachments) != 0 {
+		t.Fatalf("expected attachments to cascade, got %v", attachments)
+	}
+}
+
+func TestListChirpsVisibility(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	author := mustCreateUser(t, s, "a@example.com")
+	follower := mustCreateUser(t, s, "b@example.com")
+	stranger := mustCreateUser(t, s, "c@example.com")
+
+	for _, visibility := range []database.ChirpVisibility{
+		database.ChirpVisibilityPublic,
+		database.ChirpVisibilityFollowers,
+		database.ChirpVisibilityPrivate,
+	} {
+		_, err := s.CreateChirp(ctx, database.CreateChirpParams{
+			Body:       string(visibility),
+			UserID:     author.ID,
+			Visibility: visibility,
+		})
+		if err != nil {
+			t.Fatalf("CreateChirp failed: %v", err)
+		}
+	}
+	err := s.CreateFollow(ctx, database.CreateFollowParams{FollowerID: follower.ID, FolloweeID: author.ID})
+	if err != nil {
+		t.Fatalf("CreateFollow failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		viewer uuid.NullUUID
+		want   int
+	}{
+		{"anonymous", uuid.NullUUID{}, 1},
+		{"stranger", uuid.NullUUID{UUID: stranger.ID, Valid: true}, 1},
+		{"follower", uuid.NullUUID{UUID: follower.ID, Valid: true}, 2},
+		{"author", uuid.NullUUID{UUID: author.ID, Valid: true}, 3},
+	}
+	for _, tt := range tests {
+		chirps, err := s.ListChirps(ctx, database.ListChirpsParams{ViewerID: tt.viewer, MaxResults: 10})
+		if err != nil {
+			t.Fatalf("ListChirps failed: %v", err)
+		}
+		if len(chirps) != tt.want {
+			t.Errorf("%s: expected %d chirps, got %d", tt.name, tt.want, len(chirps))
+		}
+	}
+}
+
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "a@example.com")
+	chirp := mustCreateChirp(t, s, user.ID, "once")
+
+	err := s.CreateIdempotencyKey(ctx, database.CreateIdempotencyKeyParams{
+		UserID:  user.ID,
+		Key:     "k",
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateIdempotencyKey failed: %v", err)
+	}
+
+	args := database.GetIdempotentChirpParams{UserID: user.ID, Key: "k", NotBefore: time.Now().Add(-time.Hour)}
+	got, err := s.GetIdempotentChirp(ctx, args)
+	if err != nil || got.ID != chirp.ID {
+		t.Fatalf("expected the original chirp, got %v, %v", got, err)
+	}
+
+	args.NotBefore = time.Now().Add(time.Hour)
+	if _, err := s.GetIdempotentChirp(ctx, args); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected an expired key to be ignored, got %v", err)
+	}
+}
+
+func TestDeactivateAndPurgeUser(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "a@example.com")
+	mustCreateChirp(t, s, user.ID, "soon gone")
+
+	if _, err := s.DeactivateUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeactivateUser failed: %v", err)
+	}
+	chirps, err := s.ListChirps(ctx, database.ListChirpsParams{MaxResults: 10})
+	if err != nil || len(chirps) != 0 {
+		t.Fatalf("expected a deactivated user's chirps to be hidden, got %v, %v", chirps, err)
+	}
+
+	n, err := s.PurgeDeactivatedUsers(ctx, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true})
+	if err != nil || n != 0 {
+		t.Fatalf("expected nothing purged within the grace period, got %d, %v", n, err)
+	}
+	n, err = s.PurgeDeactivatedUsers(ctx, sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true})
+	if err != nil || n != 1 {
+		t.Fatalf("expected one user purged, got %d, %v", n, err)
+	}
+	if len(s.chirps) != 0 {
+		t.Fatalf("expected the user's chirps to be purged, %d left", len(s.chirps))
+	}
+}
+
+func TestLikedChirpsPaging(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "a@example.com")
+	first := mustCreateChirp(t, s, user.ID, "first")
+	second := mustCreateChirp(t, s, user.ID, "second")
+
+	for _, chirp := range []database.Chirp{first, second} {
+		if err := s.CreateLike(ctx, database.CreateLikeParams{UserID: user.ID, ChirpID: chirp.ID}); err != nil {
+			t.Fatalf("CreateLike failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	args := database.GetLikedChirpsParams{UserID: user.ID, PageLimit: 1}
+	chirps, err := s.GetLikedChirps(ctx, args)
+	if err != nil || len(chirps) != 1 || chirps[0].ID != second.ID {
+		t.Fatalf("expected the latest like first, got %v, %v", chirps, err)
+	}
+
+	args.PageOffset = 2
+	chirps, err = s.GetLikedChirps(ctx, args)
+	if err != nil || len(chirps) != 0 {
+		t.Fatalf("expected an empty page past the end, got %v, %v", chirps, err)
+	}
+}
+
+func TestExpiredChirps(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "a@example.com")
+	forever := mustCreateChirp(t, s, user.ID, "forever")
+	expired, err := s.CreateChirp(ctx, database.CreateChirpParams{
+		Body:      "gone",
+		UserID:    user.ID,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %v", err)
+	}
+
+	chirps, err := s.ListChirps(ctx, database.ListChirpsParams{MaxResults: 10})
+	if err != nil || len(chirps) != 1 || chirps[0].ID != forever.ID {
+		t.Fatalf("expected only the unexpired chirp, got %v, %v", chirps, err)
+	}
+
+	n, err := s.DeleteExpiredChirps(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 chirp deleted, got %d, %v", n, err)
+	}
+	if _, err := s.GetChirp(ctx, expired.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected the expired chirp to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// maxEmailLength mirrors the users_email_length check constraint.
+const maxEmailLength = 254
+
+func (s *Store) CountUsers(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.users)), nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(arg.Email) > maxEmailLength {
+		return database.User{}, errCheck("users", "users_email_length")
+	}
+	if s.emailTaken(arg.Email, uuid.Nil) {
+		return database.User{}, errUnique("users", "users_email_key")
+	}
+
+	t := now()
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      t,
+		UpdatedAt:      t,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) DeactivateUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	t := now()
+	user.UpdatedAt = t
+	user.DeactivatedAt = sql.NullTime{Time: t, Valid: true}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) DeleteAllUsers(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(len(s.users))
+	//everything else references users and cascades
+	clear(s.users)
+	clear(s.chirps)
+	clear(s.chirpRevisions)
+	clear(s.attachments)
+	clear(s.refreshTokens)
+	clear(s.apiKeys)
+	clear(s.bookmarks)
+	clear(s.likes)
+	clear(s.follows)
+	clear(s.idempotency)
+	clear(s.inviteCodeUses)
+	return n, nil
+}
+
+func (s *Store) DowngradeUserChirpyRed(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	user.UpdatedAt = now()
+	user.IsChirpyRed = false
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) EmailExists(ctx context.Context, email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.emailTaken(email, uuid.Nil), nil
+}
+
+func (s *Store) GetUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]database.GetUsersByIDsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.GetUsersByIDsRow
+	for _, user := range s.users {
+		if !slices.Contains(ids, user.ID) || user.DeactivatedAt.Valid {
+			continue
+		}
+
+		row := database.GetUsersByIDsRow{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			IsChirpyRed: user.IsChirpyRed,
+		}
+		for key := range s.follows {
+			if key.FolloweeID == user.ID {
+				row.FollowerCount++
+			}
+			if key.FollowerID == user.ID {
+				row.FollowingCount++
+			}
+		}
+		items = append(items, row)
+	}
+	slices.SortFunc(items, func(a, b database.GetUsersByIDsRow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items, nil
+}
+
+func (s *Store) ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := slices.Collect(maps.Values(s.users))
+	slices.SortFunc(items, func(a, b database.User) int {
+		if n := a.CreatedAt.Compare(b.CreatedAt); n != 0 {
+			return n
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	if int(arg.Offset) >= len(items) {
+		return nil, nil
+	}
+	return limit(items[arg.Offset:], arg.Limit), nil
+}
+
+// PurgeDeactivatedUsers deletes the users and cascades by hand what the
+// foreign keys would.
+func (s *Store) PurgeDeactivatedUsers(ctx context.Context, deactivatedAt sql.NullTime) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, user := range s.users {
+		if !user.DeactivatedAt.Valid || user.DeactivatedAt.Time.After(deactivatedAt.Time) {
+			continue
+		}
+
+		delete(s.users, id)
+		for chirpID, chirp := range s.chirps {
+			if chirp.UserID == id {
+				s.deleteChirp(chirpID)
+			}
+		}
+		for token, record := range s.refreshTokens {
+			if record.UserID == id {
+				delete(s.refreshTokens, token)
+			}
+		}
+		for keyID, apiKey := range s.apiKeys {
+			if apiKey.UserID == id {
+				delete(s.apiKeys, keyID)
+			}
+		}
+		for key := range s.bookmarks {
+			if key.UserID == id {
+				delete(s.bookmarks, key)
+			}
+		}
+		for key := range s.likes {
+			if key.UserID == id {
+				delete(s.likes, key)
+			}
+		}
+		for key := range s.follows {
+			if key.FollowerID == id || key.FolloweeID == id {
+				delete(s.follows, key)
+			}
+		}
+		for key := range s.idempotency {
+			if key.UserID == id {
+				delete(s.idempotency, key)
+			}
+		}
+		for key := range s.inviteCodeUses {
+			if key.UserID == id {
+				delete(s.inviteCodeUses, key)
+			}
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) ReactivateUser(ctx context.Context, arg database.ReactivateUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.ID]
+	if !ok || !user.DeactivatedAt.Valid || !user.DeactivatedAt.Time.After(arg.DeactivatedAt.Time) {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	user.UpdatedAt = now()
+	user.DeactivatedAt = sql.NullTime{}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) SetUserSuspended(ctx context.Context, arg database.SetUserSuspendedParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	user.UpdatedAt = now()
+	user.Suspended = arg.Suspended
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) UpdateUserEmailAndPass(ctx context.Context, arg database.UpdateUserEmailAndPassParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	if len(arg.Email) > maxEmailLength {
+		return database.User{}, errCheck("users", "users_email_length")
+	}
+	if s.emailTaken(arg.Email, arg.ID) {
+		return database.User{}, errUnique("users", "users_email_key")
+	}
+
+	user.UpdatedAt = now()
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+
+	user.LastLoginAt = sql.NullTime{Time: now(), Valid: true}
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *Store) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.ID]
+	if !ok {
+		return nil
+	}
+
+	user.UpdatedAt = now()
+	user.HashedPassword = arg.HashedPassword
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *Store) UpgradeUserChirpyRed(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	user.UpdatedAt = now()
+	user.IsChirpyRed = true
+	s.users[user.ID] = user
+	return user, nil
+}
+
+// emailTaken reports whether a user other than except has the email.
+// The caller must hold s.mu.
+func (s *Store) emailTaken(email string, except uuid.UUID) bool {
+	for _, user := range s.users {
+		if user.Email == email && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+// Package profanity masks banned words in chirps.
+package profanity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultWords is the banned word list used unless configured otherwise.
+var DefaultWords = []string{"kerfuffle", "sharbert", "fornax"}
+
+// Style controls how a banned word is masked.
+type Style int
+
+const (
+	// Fixed replaces every banned word with "****".
+	Fixed Style = iota
+	// Length replaces each character of a banned word with "*".
+	Length
+)
+
+// ParseStyle parses the CENSOR_STYLE values "fixed" and "length".
+func ParseStyle(s string) (Style, error) {
+	switch s {
+	case "", "fixed":
+		return Fixed, nil
+	case "length":
+		return Length, nil
+	}
+	return Fixed, fmt.Errorf("unknown censor style %q", s)
+}
+
+// Mode is what happens to a chirp with banned words in it.
+type Mode int
+
+const (
+	// Censor masks the banned words and posts the chirp.
+	Censor Mode = iota
+	// Reject refuses the chirp.
+	Reject
+)
+
+// ParseMode parses the PROFANITY_MODE values "censor" and "reject".
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "", "censor":
+		return Censor, nil
+	case "reject":
+		return Reject, nil
+	}
+	return Censor, fmt.Errorf("unknown profanity mode %q", s)
+}
+
+// Match is how a word in a chirp is matched against the banned words.
+type Match int
+
+const (
+	// Word only matches a banned word on its own.
+	Word Match = iota
+	// Substring matches any word with a banned word inside it, catching
+	// "superkerfuffle" at the cost of false positives, the Scunthorpe
+	// problem: an innocent word that happens to contain a banned one is
+	// censored too.
+	Substring
+)
+
+// ParseMatch parses the PROFANITY_MATCH_MODE values "word" and
+// "substring".
+func ParseMatch(s string) (Match, error) {
+	switch s {
+	case "", "word":
+		return Word, nil
+	case "substring":
+		return Substring, nil
+	}
+	return Word, fmt.Errorf("unknown profanity match mode %q", s)
+}
+
+type Filter struct {
+	Words []string
+	Style Style
+	Mode  Mode
+	Match Match
+}
+
+// Clean masks the banned words in body, reporting whether it found any.
+// Matching is case-insensitive, on whole words or, with Substring, on any
+// word containing a banned one, which is masked whole.
+func (f Filter) Clean(body string) (string, bool) {
+	chirpWords := strings.Fields(body)
+	cleanedWords := []string{}
+	cleaned := false
+
+	for _, word := range chirpWords {
+		if _, ok := f.banned(word); ok {
+			cleanedWords = append(cleanedWords, f.mask(word))
+			cleaned = true
+		} else {
+			cleanedWords = append(cleanedWords, word)
+		}
+	}
+
+	return strings.Join(cleanedWords, " "), cleaned
+}
+
+// Find returns the banned words in body, lowercased and each only once, in
+// the order they first appear. It matches like Clean, and reports the
+// banned word itself when it was found inside another.
+func (f Filter) Find(body string) []string {
+	found := []string{}
+	for _, word := range strings.Fields(body) {
+		if banned, ok := f.banned(word); ok && !slices.Contains(found, banned) {
+			found = append(found, banned)
+		}
+	}
+	return found
+}
+
+// banned returns the banned word matching word, if any.
+func (f Filter) banned(word string) (string, bool) {
+	word = strings.ToLower(word)
+	if f.Match == Substring {
+		for _, banned := range f.Words {
+			if strings.Contains(word, banned) {
+				return banned, true
+			}
+		}
+		return "", false
+	}
+	return word, slices.Contains(f.Words, word)
+}
+
+func (f Filter) mask(word string) string {
+	if f.Style == Length {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	}
+	return "****"
+}
@@ -0,0 +1,127 @@
+package profanity
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCleanFixed(t *testing.T) {
+	f := Filter{Words: DefaultWords, Style: Fixed}
+
+	cases := map[string]string{
+		"I had a kerfuffle today": "I had a **** today",
+		"Sharbert is great":       "**** is great",
+		"fornax":                  "****",
+	}
+
+	for input, want := range cases {
+		got, cleaned := f.Clean(input)
+		if !cleaned {
+			t.Errorf("Clean(%q) reported nothing cleaned", input)
+		}
+		if got != want {
+			t.Errorf("Clean(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCleanLength(t *testing.T) {
+	f := Filter{Words: []string{"ab", "fornax", "sharbert", "kerfuffle"}, Style: Length}
+
+	cases := map[string]string{
+		"ab cd":                   "** cd",
+		"fornax":                  "******",
+		"Sharbert is great":       "******** is great",
+		"I had a kerfuffle today": "I had a ********* today",
+	}
+
+	for input, want := range cases {
+		got, cleaned := f.Clean(input)
+		if !cleaned {
+			t.Errorf("Clean(%q) reported nothing cleaned", input)
+		}
+		if got != want {
+			t.Errorf("Clean(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCleanNoMatch(t *testing.T) {
+	for _, style := range []Style{Fixed, Length} {
+		f := Filter{Words: DefaultWords, Style: style}
+		got, cleaned := f.Clean("a perfectly nice chirp")
+		if cleaned || got != "a perfectly nice chirp" {
+			t.Errorf("style %d: unexpected clean %q", style, got)
+		}
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	cases := map[string]Style{"": Fixed, "fixed": Fixed, "length": Length}
+	for input, want := range cases {
+		got, err := ParseStyle(input)
+		if err != nil || got != want {
+			t.Errorf("ParseStyle(%q) = %v, %v", input, got, err)
+		}
+	}
+
+	if _, err := ParseStyle("stars"); err == nil {
+		t.Errorf("expected error for unknown style")
+	}
+}
+
+func TestFind(t *testing.T) {
+	f := Filter{Words: DefaultWords}
+
+	got := f.Find("Fornax and kerfuffle, then fornax again")
+	if !slices.Equal(got, []string{"fornax"}) {
+		t.Errorf("Find = %q, want only whole words, each once", got)
+	}
+	if got := f.Find("nothing to see here"); len(got) != 0 {
+		t.Errorf("Find on a clean chirp = %q, want none", got)
+	}
+}
+
+func TestCleanMatch(t *testing.T) {
+	cases := []struct {
+		match Match
+		input string
+		want  string
+	}{
+		{Word, "what a kerfuffle", "what a ****"},
+		{Word, "superkerfuffle and kerfufflez", "superkerfuffle and kerfufflez"},
+		{Substring, "what a kerfuffle", "what a ****"},
+		{Substring, "superkerfuffle and Kerfufflez", "**** and ****"},
+		{Substring, "a perfectly nice chirp", "a perfectly nice chirp"},
+	}
+
+	for _, c := range cases {
+		f := Filter{Words: DefaultWords, Match: c.match}
+		if got, _ := f.Clean(c.input); got != c.want {
+			t.Errorf("match %d: Clean(%q) = %q, want %q", c.match, c.input, got, c.want)
+		}
+	}
+}
+
+func TestFindSubstring(t *testing.T) {
+	f := Filter{Words: DefaultWords, Match: Substring}
+
+	got := f.Find("superkerfuffle, Fornaxes and another kerfuffle")
+	if !slices.Equal(got, []string{"kerfuffle", "fornax"}) {
+		t.Errorf("Find = %q, want the banned words found inside others, each once", got)
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	cases := map[string]Match{"": Word, "word": Word, "substring": Substring}
+	for input, want := range cases {
+		got, err := ParseMatch(input)
+		if err != nil || got != want {
+			t.Errorf("ParseMatch(%q) = %v, %v", input, got, err)
+		}
+	}
+
+	if _, err := ParseMatch("regex"); err == nil {
+		t.Errorf("expected error for unknown match mode")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+type InviteCode struct {
+	Code      string     `json:"code"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   int32      `json:"max_uses"`
+	Uses      int32      `json:"uses"`
+}
+
+func inviteCodeFromDB(dbInvite database.InviteCode) InviteCode {
+	invite := InviteCode{
+		Code:      dbInvite.Code,
+		CreatedAt: dbInvite.CreatedAt,
+		MaxUses:   dbInvite.MaxUses,
+		Uses:      dbInvite.Uses,
+	}
+	if dbInvite.ExpiresAt.Valid {
+		invite.ExpiresAt = &dbInvite.ExpiresAt.Time
+	}
+	return invite
+}
+
+// handlerCreateInviteCode makes a code for ALLOW_SIGNUPS=invite. It is good
+// for one signup and never expires unless max_uses and expires_in say
+// otherwise.
+func (a *apiConfig) handlerCreateInviteCode(w http.ResponseWriter, req *http.Request) {
+	type reqBody struct {
+		ExpiresIn string `json:"expires_in"`
+		MaxUses   *int32 `json:"max_uses"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerCreateInviteCode, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	createArgs := database.CreateInviteCodeParams{MaxUses: 1}
+	if body.MaxUses != nil {
+		if *body.MaxUses < 1 {
+			respondWithError(w, http.StatusBadRequest, "max_uses must be at least 1")
+			return
+		}
+		createArgs.MaxUses = *body.MaxUses
+	}
+	if body.ExpiresIn != "" {
+		d, err := time.ParseDuration(body.ExpiresIn)
+		if err != nil || d <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid expires_in")
+			return
+		}
+		createArgs.ExpiresAt = sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}
+	}
+
+	code, err := auth.MakeInviteCode()
+	if err != nil {
+		log.Printf("in handlerCreateInviteCode, unable to make code: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	createArgs.Code = code
+
+	dbInvite, err := a.dbQueries.CreateInviteCode(req.Context(), createArgs)
+	if err != nil {
+		log.Printf("in handlerCreateInviteCode, unable to add to database: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("invite code for %d signups created by %s", dbInvite.MaxUses, req.RemoteAddr)
+	respondWithJSON(w, http.StatusCreated, inviteCodeFromDB(dbInvite))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func TestInviteOnlySignups(t *testing.T) {
+	a, store, _, _ := newTestAPI(t)
+	params, err := auth.NewHashParams(auth.MinHashMemory, 1, 1)
+	if err != nil {
+		t.Fatalf("NewHashParams failed: %v", err)
+	}
+	a.hashParams = params
+	a.inviteOnly = true
+	signup := func(email, code string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := `{"email":"` + email + `","password":"hunter2","invite_code":"` + code + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.handlerUsers(w, req)
+		return w
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/invite_codes", strings.NewReader(`{"expires_in":"1h"}`))
+	w := httptest.NewRecorder()
+	a.handlerCreateInviteCode(w, req)
+	var invite InviteCode
+	if err := json.Unmarshal(w.Body.Bytes(), &invite); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("expected a new invite code, got %d %s", w.Code, w.Body)
+	}
+	if invite.MaxUses != 1 || invite.ExpiresAt == nil {
+		t.Fatalf("expected a single use code with an expiry, got %+v", invite)
+	}
+
+	if w := signup("b@example.com", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected a missing code to be forbidden, got %d %s", w.Code, w.Body)
+	}
+	if w := signup("b@example.com", "not-a-code"); w.Code != http.StatusForbidden {
+		t.Errorf("expected an unknown code to be forbidden, got %d %s", w.Code, w.Body)
+	}
+	//a signup that fails doesn't spend the code
+	if w := signup("a@example.com", invite.Code); w.Code != http.StatusConflict {
+		t.Errorf("expected a taken email to conflict, got %d %s", w.Code, w.Body)
+	}
+	if w := signup("b@example.com", invite.Code); w.Code != http.StatusCreated {
+		t.Fatalf("expected the code to sign up a user, got %d %s", w.Code, w.Body)
+	}
+	if w := signup("c@example.com", invite.Code); w.Code != http.StatusForbidden {
+		t.Errorf("expected a used up code to be forbidden, got %d %s", w.Code, w.Body)
+	}
+
+	expiredArgs := database.CreateInviteCodeParams{
+		Code:      "expired",
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(-time.Minute), Valid: true},
+		MaxUses:   5,
+	}
+	if _, err := store.CreateInviteCode(context.Background(), expiredArgs); err != nil {
+		t.Fatalf("CreateInviteCode failed: %v", err)
+	}
+	if w := signup("c@example.com", "expired"); w.Code != http.StatusForbidden {
+		t.Errorf("expected an expired code to be forbidden, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateInviteCodeRejectsBadLimits(t *testing.T) {
+	a, _, _, _ := newTestAPI(t)
+	for _, body := range []string{`{"max_uses":0}`, `{"expires_in":"-1h"}`, `{"expires_in":"soon"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/invite_codes", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.handlerCreateInviteCode(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected a 400, got %d %s", body, w.Code, w.Body)
+		}
+	}
+}
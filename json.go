@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// prettyJSON indents JSON responses for reading with curl. It costs
+// bandwidth, so main only turns it on with PRETTY_JSON on the dev platform.
+var prettyJSON bool
+
+// strictJSON refuses request bodies with fields the handler doesn't know,
+// so a typo like "emial" is an error rather than silently ignored. main
+// turns it on with STRICT_JSON.
+var strictJSON bool
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	jsonDat, err := marshalJSON(payload)
+	if err != nil {
+		log.Printf("in respondWithJSON, unable to encode JSON: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(jsonDat)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	respondWithJSON(w, code, errorResponse{Error: msg})
+}
+
+func marshalJSON(payload any) ([]byte, error) {
+	if prettyJSON {
+		return json.MarshalIndent(payload, "", "  ")
+	}
+	return json.Marshal(payload)
+}
+
+// newJSONDecoder decodes a request body, honouring strictJSON. Handlers
+// check decode errors with respondWithUnknownField first.
+func newJSONDecoder(r io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(r)
+	if strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder
+}
+
+// respondWithUnknownField answers a decode error caused by an unknown field
+// with a 400 naming it, reporting whether it did. Other errors are left to
+// the handler.
+func respondWithUnknownField(w http.ResponseWriter, err error) bool {
+	//encoding/json has no error type for this, only the message
+	raw, ok := strings.CutPrefix(fmt.Sprint(err), "json: unknown field ")
+	if err == nil || !ok {
+		return false
+	}
+	field, unquoteErr := strconv.Unquote(raw)
+	if unquoteErr != nil {
+		field = raw
+	}
+	log.Printf("refused request body with unknown field %q", field)
+	respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown field %q", field))
+	return true
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStrictJSON(t *testing.T) {
+	a, _, _, _ := newTestAPI(t)
+	post := func() *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"emial":"b@example.com","password":"hunter2"}`))
+		w := httptest.NewRecorder()
+		a.handlerUsers(w, req)
+		return w
+	}
+
+	if w := post(); strings.Contains(w.Body.String(), "Unknown field") {
+		t.Fatalf("expected unknown fields to be ignored by default, got %s", w.Body)
+	}
+
+	strictJSON = true
+	t.Cleanup(func() { strictJSON = false })
+	w := post()
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `Unknown field \"emial\"`) {
+		t.Errorf("expected a 400 naming the field, got %d %s", w.Code, w.Body)
+	}
+}
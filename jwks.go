@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/kbm-ky/chirpy/internal/auth"
+)
+
+// handlerJWKS publishes the keys access tokens are verified with, so other
+// services can check tokens without sharing a secret. It is only routed in
+// RS256 mode.
+func (a *apiConfig) handlerJWKS(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Keys []auth.JWK `json:"keys"`
+	}
+	respondWithJSON(w, http.StatusOK, response{Keys: a.jwtKeys.JWKs()})
+}
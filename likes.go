@@ -0,0 +1,182 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// LikeCount is what liking and unliking answer with, so clients can show
+// the new count without refetching the chirp.
+type LikeCount struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	Likes   int64     `json:"likes"`
+}
+
+// handlerPostLikes only ever adds a like, liking twice changes nothing, and
+// answers with the chirp's like count.
+func (a *apiConfig) handlerPostLikes(w http.ResponseWriter, req *http.Request) {
+	chirpID, ok := a.addLike(w, req)
+	if !ok {
+		return
+	}
+	a.respondWithLikeCount(w, req, chirpID)
+}
+
+// handlerDeleteLikes only ever removes a like and answers with the chirp's
+// like count, the same whether or not there was a like to remove.
+func (a *apiConfig) handlerDeleteLikes(w http.ResponseWriter, req *http.Request) {
+	chirpID, ok := a.removeLike(w, req)
+	if !ok {
+		return
+	}
+	a.respondWithLikeCount(w, req, chirpID)
+}
+
+func (a *apiConfig) respondWithLikeCount(w http.ResponseWriter, req *http.Request, chirpID uuid.UUID) {
+	count, err := a.dbQueries.CountLikes(req.Context(), chirpID)
+	if err != nil {
+		log.Printf("in respondWithLikeCount, unable to count likes: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, LikeCount{ChirpID: chirpID, Likes: count})
+}
+
+// likedChirp finds the chirp in the path for the authenticated user to like
+// or unlike, treating one they can't see as missing, before anything is
+// changed. It answers any failure itself and reports whether it found one.
+func (a *apiConfig) likedChirp(w http.ResponseWriter, req *http.Request) (userID, chirpID uuid.UUID, ok bool) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in likedChirp, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	//Get chirp id
+	chirpID, err = uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in likedChirp, could not parse chirp id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	//Does the chirp exist, as far as the user can tell?
+	chirp, err := a.dbQueries.GetChirp(req.Context(), chirpID)
+	if err != nil {
+		log.Printf("in likedChirp, could not get chirp: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	visible, err := canView(req.Context(), a.dbQueries, uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		log.Printf("in likedChirp, unable to check visibility: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !visible {
+		w.WriteHeader(http.StatusNotFound)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, chirpID, true
+}
+
+// addLike likes the chirp in the path for the authenticated user, if they
+// can see it. It answers any failure itself and reports whether it went
+// through.
+func (a *apiConfig) addLike(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	userID, chirpID, ok := a.likedChirp(w, req)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	//Liking twice is a no-op
+	likeArgs := database.CreateLikeParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+	err := a.dbQueries.CreateLike(req.Context(), likeArgs)
+	if err != nil {
+		log.Printf("in addLike, unable to create like: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return uuid.Nil, false
+	}
+	return chirpID, true
+}
+
+// removeLike takes back the authenticated user's like of the chirp in the
+// path, if they can see it and there is one. It answers any failure itself
+// and reports whether it went through.
+func (a *apiConfig) removeLike(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	userID, chirpID, ok := a.likedChirp(w, req)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	deleteArgs := database.DeleteLikeParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+	err := a.dbQueries.DeleteLike(req.Context(), deleteArgs)
+	if err != nil {
+		log.Printf("in removeLike, unable to delete like: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return uuid.Nil, false
+	}
+	return chirpID, true
+}
+
+// handlerGetUserLikes pages through the chirps a user has liked, most
+// recently liked first. Likes are public for now; the reader only gets the
+// chirps they could see anyway.
+func (a *apiConfig) handlerGetUserLikes(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerGetUserLikes, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dbUser, err := a.readQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetUserLikes, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+	if dbUser.DeactivatedAt.Valid {
+		log.Printf("in handlerGetUserLikes, deactivated user: %s", userID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	query := req.URL.Query()
+	viewer := a.viewer(req)
+	likedArgs := database.GetLikedChirpsParams{
+		UserID:     userID,
+		ViewerID:   viewer,
+		PageLimit:  a.pageSizes.limit(query),
+		PageOffset: pageOffset(query),
+	}
+	setPageSize(w, likedArgs.PageLimit)
+
+	dbChirps, err := a.readQueries.GetLikedChirps(req.Context(), likedArgs)
+	if err != nil {
+		log.Printf("in handlerGetUserLikes, unable to get liked chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	//nothing liked yet is an empty page, not a 404
+	chirps, err := chirpsFromDB(req.Context(), a.readQueries, viewer, dbChirps)
+	if err != nil {
+		log.Printf("in handlerGetUserLikes, unable to load chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
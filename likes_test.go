@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func TestLikesEndpoints(t *testing.T) {
+	a, store, user, token := newTestAPI(t)
+	chirp, err := store.CreateChirp(context.Background(), database.CreateChirpParams{Body: "hello", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %v", err)
+	}
+
+	send := func(method string, handler http.HandlerFunc) (int, LikeCount) {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/chirps/"+chirp.ID.String()+"/likes", nil)
+		req.SetPathValue("id", chirp.ID.String())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		var count LikeCount
+		json.Unmarshal(w.Body.Bytes(), &count)
+		return w.Code, count
+	}
+
+	for range 2 {
+		if code, count := send(http.MethodPost, a.handlerPostLikes); code != http.StatusOK || count.Likes != 1 {
+			t.Errorf("expected liking to leave 1 like, got %d %+v", code, count)
+		}
+	}
+	for range 2 {
+		if code, count := send(http.MethodDelete, a.handlerDeleteLikes); code != http.StatusOK || count.Likes != 0 {
+			t.Errorf("expected unliking to leave 0 likes, got %d %+v", code, count)
+		}
+	}
+}
+
+func TestUnlikeChecksChirpFirst(t *testing.T) {
+	ctx := context.Background()
+	a, store, user, token := newTestAPI(t)
+	other, err := store.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	hidden, err := store.CreateChirp(ctx, database.CreateChirpParams{Body: "hello", UserID: other.ID, Visibility: database.ChirpVisibilityPrivate})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %v", err)
+	}
+	//liked before it went private
+	if err := store.CreateLike(ctx, database.CreateLikeParams{UserID: user.ID, ChirpID: hidden.ID}); err != nil {
+		t.Fatalf("CreateLike failed: %v", err)
+	}
+
+	for _, id := range []string{hidden.ID.String(), uuid.NewString()} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+id+"/likes", nil)
+		req.SetPathValue("id", id)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		a.handlerDeleteLikes(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected a 404, got %d %s", id, w.Code, w.Body)
+		}
+	}
+
+	if count, err := store.CountLikes(ctx, hidden.ID); err != nil || count != 1 {
+		t.Errorf("expected the like on the hidden chirp to be left alone, got %d, %v", count, err)
+	}
+}
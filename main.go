@@ -1,90 +1,540 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"slices"
-	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/alexedwards/argon2id"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/kbm-ky/chirpy/internal/auth"
 	"github.com/kbm-ky/chirpy/internal/database"
+	"github.com/kbm-ky/chirpy/internal/lang"
+	"github.com/kbm-ky/chirpy/internal/memstore"
+	"github.com/kbm-ky/chirpy/internal/profanity"
 	_ "github.com/lib/pq"
 )
 
 func main() {
 	godotenv.Load()
-	dbURL := os.Getenv("DB_URL")
-	db, err := sql.Open("postgres", dbURL)
+	logOutput, err := logOutputFromEnv()
 	if err != nil {
-		log.Printf("unable to open database: %v", err)
-		os.Exit(1)
+		log.Fatalf("unable to configure logging: %v", err)
+	}
+	log.SetOutput(logOutput)
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("unable to configure tracing: %v", err)
+	}
+
+	if missing := missingConfig(); len(missing) > 0 {
+		log.Fatalf("missing required configuration: %s", strings.Join(missing, ", "))
 	}
 
-	dbQueries := database.New(db)
+	dbURL, err := envSecret("DB_URL")
+	if err != nil {
+		log.Fatalf("unable to configure database: %v", err)
+	}
+	var dbQueries database.Querier
+	var runTx txRunner
+	var pinger dbPinger
+	if dbURL == "memory" {
+		log.Printf("using in-memory store, nothing will be persisted")
+		store := memstore.New()
+		dbQueries = store
+		runTx = store.WithTx
+		pinger = store
+	} else {
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			log.Printf("unable to open database: %v", err)
+			os.Exit(1)
+		}
+		dbQueries = database.New(db)
+		runTx = postgresTx(db)
+		pinger = db
+	}
+
+	//reads go to the replica when there is one
+	readQueries := dbQueries
+	if replicaURL := os.Getenv("DB_REPLICA_URL"); replicaURL != "" {
+		replica, err := sql.Open("postgres", replicaURL)
+		if err != nil {
+			log.Printf("unable to open replica database: %v", err)
+			os.Exit(1)
+		}
+		readQueries = database.New(replica)
+	}
+
+	queryTimeout, err := envDuration("DB_QUERY_TIMEOUT", 5*time.Second)
+	if err != nil {
+		log.Fatalf("unable to configure database: %v", err)
+	}
+	slowQueryMS, err := envUint("SLOW_QUERY_MS", 0, 31)
+	if err != nil {
+		log.Fatalf("unable to configure database: %v", err)
+	}
+	slowQuery := time.Duration(slowQueryMS) * time.Millisecond
+	dbQueries = newTimedQuerier(dbQueries, queryTimeout, slowQuery)
+	readQueries = newTimedQuerier(readQueries, queryTimeout, slowQuery)
+	runTx = runTx.timed(queryTimeout, slowQuery)
 
 	fmt.Printf("Starting server...\n")
 
 	serveMux := http.NewServeMux()
 	server := http.Server{
-		Addr:    ":8080",
-		Handler: serveMux,
+		Addr: ":8080",
 	}
 
 	platform := os.Getenv("PLATFORM")
-	secret := os.Getenv("SECRET")
-	polkaKey := os.Getenv("POLKA_KEY")
-	apiConfig := apiConfig{
-		dbQueries: dbQueries,
-		platform:  platform,
-		secret:    secret,
-		polkaKey:  polkaKey,
+	debug, err := envBool("DEBUG", false)
+	if err != nil {
+		log.Fatalf("unable to configure debugging: %v", err)
+	}
+	prettyJSON, err = envBool("PRETTY_JSON", false)
+	if err != nil {
+		log.Fatalf("unable to configure JSON responses: %v", err)
+	}
+	if prettyJSON && platform != "dev" {
+		log.Printf("ignoring PRETTY_JSON, it is only honoured with PLATFORM=dev")
+		prettyJSON = false
+	}
+	strictJSON, err = envBool("STRICT_JSON", false)
+	if err != nil {
+		log.Fatalf("unable to configure JSON requests: %v", err)
+	}
+	secret, err := envSecret("SECRET")
+	if err != nil {
+		log.Fatalf("unable to configure access tokens: %v", err)
+	}
+	jwtKeys, err := jwtKeysFromEnv(secret)
+	if err != nil {
+		log.Fatalf("unable to configure access tokens: %v", err)
+	}
+	jwtIssuer := os.Getenv("JWT_ISSUER")
+	if jwtIssuer == "" {
+		jwtIssuer = auth.DefaultIssuer
+	}
+	polkaKey, err := envSecret("POLKA_KEY")
+	if err != nil {
+		log.Fatalf("unable to configure webhooks: %v", err)
+	}
+	if polkaKey == "" {
+		log.Printf("warning: POLKA_KEY is not set, Polka webhooks will all be refused")
+	}
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	hashParams, err := hashParamsFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure password hashing: %v", err)
+	}
+	rehashOnLogin, err := envBool("REHASH_ON_LOGIN", true)
+	if err != nil {
+		log.Fatalf("unable to configure password hashing: %v", err)
+	}
+	allowSignups, inviteOnly, err := signupsFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure signups: %v", err)
+	}
+	refreshCookie, err := envBool("REFRESH_TOKEN_COOKIE", false)
+	if err != nil {
+		log.Fatalf("unable to configure refresh tokens: %v", err)
+	}
+	censorStyle, err := profanity.ParseStyle(os.Getenv("CENSOR_STYLE"))
+	if err != nil {
+		log.Fatalf("unable to configure profanity filter: %v", err)
+	}
+	profanityMode, err := profanity.ParseMode(os.Getenv("PROFANITY_MODE"))
+	if err != nil {
+		log.Fatalf("unable to configure profanity filter: %v", err)
+	}
+	profanityMatch, err := profanity.ParseMatch(os.Getenv("PROFANITY_MATCH_MODE"))
+	if err != nil {
+		log.Fatalf("unable to configure profanity filter: %v", err)
+	}
+	requireReadAuth, err := envBool("REQUIRE_AUTH_FOR_READ", false)
+	if err != nil {
+		log.Fatalf("unable to configure read access: %v", err)
+	}
+	maintenance, err := envBool("MAINTENANCE_MODE", false)
+	if err != nil {
+		log.Fatalf("unable to configure maintenance mode: %v", err)
+	}
+	cors, err := corsFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure CORS: %v", err)
+	}
+	var webhooks *webhookDispatcher
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+		webhookSecret := os.Getenv("WEBHOOK_SECRET")
+		if webhookSecret == "" {
+			log.Fatalf("unable to configure webhooks: WEBHOOK_SECRET is required with WEBHOOK_URL")
+		}
+		webhooks = newWebhookDispatcher(webhookURL, webhookSecret)
+	}
+	//e.g. /app/, for a landing page instead of the JSON
+	rootRedirect := os.Getenv("ROOT_REDIRECT")
+	publicBaseURL, err := envBaseURL("PUBLIC_BASE_URL")
+	if err != nil {
+		log.Fatalf("unable to configure links: %v", err)
+	}
+	if publicBaseURL == "" && platform != "dev" {
+		log.Printf("warning: PUBLIC_BASE_URL is not set, feeds will be unavailable")
+	}
+	healthzBody := os.Getenv("HEALTHZ_BODY")
+	if healthzBody == "" {
+		healthzBody = "OK"
+	}
+	healthzContentType := os.Getenv("HEALTHZ_CONTENT_TYPE")
+	if healthzContentType == "" {
+		healthzContentType = "text/plain; charset=utf-8"
+	}
+	var attachmentHosts []string
+	for _, host := range envList("ALLOWED_ATTACHMENT_HOSTS") {
+		attachmentHosts = append(attachmentHosts, strings.ToLower(host))
+	}
+	idempotencyTTL, err := envDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	if err != nil {
+		log.Fatalf("unable to configure idempotency keys: %v", err)
+	}
+	minChirpLength, err := envUint("CHIRP_MIN_LENGTH", defaultMinChirpLength, 31)
+	if err != nil {
+		log.Fatalf("unable to configure chirp length: %v", err)
+	}
+	maxChirpLength, err := envUint("CHIRP_MAX_LENGTH", defaultMaxChirpLength, 31)
+	if err != nil || maxChirpLength < minChirpLength {
+		log.Fatalf("unable to configure chirp length: CHIRP_MAX_LENGTH must be an integer no less than CHIRP_MIN_LENGTH")
+	}
+	redChirpLength, err := envUint("CHIRP_MAX_LENGTH_RED", defaultRedChirpLength, 31)
+	if err != nil || redChirpLength < minChirpLength {
+		log.Fatalf("unable to configure chirp length: CHIRP_MAX_LENGTH_RED must be an integer no less than CHIRP_MIN_LENGTH")
+	}
+	editWindow, err := envWindow("EDIT_WINDOW")
+	if err != nil {
+		log.Fatalf("unable to configure chirp edits: %v", err)
+	}
+	deleteWindow, err := envWindow("DELETE_WINDOW")
+	if err != nil {
+		log.Fatalf("unable to configure chirp deletes: %v", err)
+	}
+	defaultChirpTTL, err := envWindow("DEFAULT_CHIRP_TTL")
+	if err != nil {
+		log.Fatalf("unable to configure chirp expiry: %v", err)
+	}
+	duplicateWindow, err := envWindow("DISALLOW_DUPLICATE_WINDOW")
+	if err != nil {
+		log.Fatalf("unable to configure duplicate chirps: %v", err)
+	}
+	trustedProxies, err := trustedProxiesFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure proxies: %v", err)
+	}
+	rateLimitWindow, err := envDuration("RATE_LIMIT_WINDOW", time.Minute)
+	if err != nil {
+		log.Fatalf("unable to configure rate limits: %v", err)
+	}
+	loginRateLimit, err := envUint("LOGIN_RATE_LIMIT", 10, 31)
+	if err != nil {
+		log.Fatalf("unable to configure rate limits: %v", err)
+	}
+	chirpRateLimit, err := envUint("CHIRP_RATE_LIMIT", 30, 31)
+	if err != nil {
+		log.Fatalf("unable to configure rate limits: %v", err)
+	}
+	redChirpRateLimit, err := envUint("CHIRP_RATE_LIMIT_RED", 0, 31)
+	if err != nil {
+		log.Fatalf("unable to configure rate limits: %v", err)
+	}
+	gzipMinSize, err := envUint("GZIP_MIN_SIZE", defaultGzipMinSize, 31)
+	if err != nil {
+		log.Fatalf("unable to configure compression: %v", err)
+	}
+	maxSessions, err := envUint("MAX_SESSIONS_PER_USER", 0, 31)
+	if err != nil {
+		log.Fatalf("unable to configure sessions: %v", err)
 	}
+	rateLimits, err := rateLimitBackendFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure rate limits: %v", err)
+	}
+	features, err := featuresFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure features: %v", err)
+	}
+	pageSizes, err := pageSizesFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure pagination: %v", err)
+	}
+	deletionGrace, err := envDuration("DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	if err != nil {
+		log.Fatalf("unable to configure account deletion: %v", err)
+	}
+	maxChirps, err := envUint("MAX_CHIRPS", 1000, 31)
+	if err != nil || maxChirps == 0 {
+		log.Fatalf("unable to configure chirp listing: MAX_CHIRPS must be a positive integer")
+	}
+	apiConfig := apiConfig{
+		dbQueries:     dbQueries,
+		readQueries:   readQueries,
+		runTx:         runTx,
+		pinger:        pinger,
+		platform:      platform,
+		jwtKeys:       jwtKeys,
+		jwtIssuer:     jwtIssuer,
+		polkaKey:      polkaKey,
+		adminToken:    adminToken,
+		hashParams:    hashParams,
+		rehashOnLogin: rehashOnLogin,
+		allowSignups:  allowSignups,
+		inviteOnly:    inviteOnly,
+		refreshCookie: refreshCookie,
+		maxSessions:   int(maxSessions),
+		profanity: profanity.Filter{
+			Words: profanity.DefaultWords,
+			Style: censorStyle,
+			Mode:  profanityMode,
+			Match: profanityMatch,
+		},
+		maxChirps:       int32(maxChirps),
+		requireReadAuth: requireReadAuth,
+		webhooks:        webhooks,
+		idempotencyTTL:  idempotencyTTL,
+		attachmentHosts: attachmentHosts,
+		minChirpLength:  int(minChirpLength),
+		maxChirpLength:  int(maxChirpLength),
+		redChirpLength:  int(redChirpLength),
+		editWindow:      editWindow,
+		deleteWindow:    deleteWindow,
+		defaultChirpTTL: defaultChirpTTL,
+		duplicateWindow: duplicateWindow,
+		trustedProxies:  trustedProxies,
+		loginLimiter:    rateLimits.limiter("login", int(loginRateLimit), rateLimitWindow),
+		chirpLimiter:    rateLimits.limiter("chirp", int(chirpRateLimit), rateLimitWindow),
+		redChirpLimiter: rateLimits.limiter("chirp_red", int(redChirpRateLimit), rateLimitWindow),
+		rootRedirect:    rootRedirect,
+		publicBaseURL:   publicBaseURL,
+		features:        features,
+		pageSizes:       pageSizes,
+		deletionGrace:   deletionGrace,
+		healthz: healthzResponse{
+			body:        healthzBody,
+			contentType: healthzContentType,
+		},
+	}
+	apiConfig.maintenance.Store(maintenance)
+	go apiConfig.purgeDeactivatedUsers()
+	go apiConfig.deleteExpiredChirps()
+	serveMux.HandleFunc("GET /{$}", apiConfig.handlerRoot)
 	serveMux.Handle("/app/", apiConfig.middlewareMetricsInc(handlerApp("/app", ".")))
-	serveMux.HandleFunc("GET /api/healthz", handlerReadiness)
-	serveMux.HandleFunc("POST /api/users", apiConfig.handlerUsers)
-	serveMux.HandleFunc("PUT /api/users", apiConfig.handlerPutUsers)
-	serveMux.HandleFunc("POST /api/chirps", apiConfig.handlerChirps)
-	serveMux.HandleFunc("GET /api/chirps", apiConfig.handlerGetChirps)
-	serveMux.HandleFunc("GET /api/chirps/{id}", apiConfig.handlerGetChirp)
+	serveMux.HandleFunc("GET /api/livez", handlerLiveness)
+	if len(jwtKeys.JWKs()) > 0 {
+		serveMux.HandleFunc("GET /.well-known/jwks.json", apiConfig.handlerJWKS)
+	}
+	serveMux.HandleFunc("GET /api/healthz", apiConfig.handlerReadiness)
+	serveMux.HandleFunc("GET /api/readyz", apiConfig.handlerReadiness)
+	serveMux.HandleFunc("GET /api/config", apiConfig.handlerGetConfig)
+	serveMux.Handle("GET /api/available", requireFeature(apiConfig.features.Availability, apiConfig.handlerAvailable))
+	serveMux.Handle("POST /api/users", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerUsers)))
+	serveMux.Handle("PUT /api/users", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerPutUsers)))
+	serveMux.HandleFunc("DELETE /api/users", apiConfig.handlerDeactivateUser)
+	serveMux.Handle("POST /api/users/reactivate", maxBytes(defaultBodyLimit)(apiConfig.rateLimitByIP(apiConfig.loginLimiter, apiConfig.handlerReactivateUser)))
+	serveMux.Handle("POST /api/users/password", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerChangePassword)))
+	serveMux.HandleFunc("GET /api/users/{id}", apiConfig.handlerGetUser)
+	serveMux.Handle("POST /api/users/batch", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerGetUsersBatch)))
+	serveMux.HandleFunc("GET /api/users/{id}/stats", apiConfig.handlerGetUserStats)
+	serveMux.Handle("POST /api/users/{id}/follow", requireFeature(apiConfig.features.Follows, apiConfig.handlerFollow))
+	serveMux.Handle("DELETE /api/users/{id}/follow", requireFeature(apiConfig.features.Follows, apiConfig.handlerUnfollow))
+	serveMux.Handle("GET /api/users/{id}/likes", requireFeature(apiConfig.features.Likes, apiConfig.middlewareReadAuth(apiConfig.handlerGetUserLikes).ServeHTTP))
+	serveMux.Handle("POST /api/chirps", maxBytes(chirpBodyLimit)(http.HandlerFunc(apiConfig.handlerChirps)))
+	serveMux.Handle("GET /api/chirps", apiConfig.middlewareReadAuth(apiConfig.handlerGetChirps))
+	serveMux.Handle("POST /api/chirps/validate", maxBytes(chirpBodyLimit)(http.HandlerFunc(apiConfig.handlerValidateChirp)))
+	serveMux.Handle("GET /api/chirps/{id}", apiConfig.middlewareReadAuth(apiConfig.handlerGetChirp))
+	serveMux.Handle("POST /api/chirps/batch", maxBytes(defaultBodyLimit)(apiConfig.middlewareReadAuth(apiConfig.handlerGetChirpsBatch)))
+	serveMux.Handle("PUT /api/chirps/{id}", maxBytes(chirpBodyLimit)(http.HandlerFunc(apiConfig.handlerEditChirp)))
 	serveMux.HandleFunc("DELETE /api/chirps/{id}", apiConfig.handlerDeleteChirp)
-	serveMux.HandleFunc("POST /api/login", apiConfig.handlerLogin)
+	serveMux.HandleFunc("GET /api/chirps/{id}/revisions", apiConfig.handlerGetChirpRevisions)
+	serveMux.Handle("POST /api/chirps/{id}/bookmark", requireFeature(apiConfig.features.Bookmarks, apiConfig.handlerCreateBookmark))
+	serveMux.Handle("DELETE /api/chirps/{id}/bookmark", requireFeature(apiConfig.features.Bookmarks, apiConfig.handlerDeleteBookmark))
+	serveMux.Handle("POST /api/chirps/{id}/likes", requireFeature(apiConfig.features.Likes, apiConfig.handlerPostLikes))
+	serveMux.Handle("DELETE /api/chirps/{id}/likes", requireFeature(apiConfig.features.Likes, apiConfig.handlerDeleteLikes))
+	//the original singular path, kept as an alias
+	serveMux.Handle("POST /api/chirps/{id}/like", requireFeature(apiConfig.features.Likes, apiConfig.handlerPostLikes))
+	serveMux.Handle("DELETE /api/chirps/{id}/like", requireFeature(apiConfig.features.Likes, apiConfig.handlerDeleteLikes))
+	if debug {
+		serveMux.HandleFunc("GET /api/whoami", apiConfig.handlerWhoami)
+	}
+	serveMux.HandleFunc("GET /api/me", apiConfig.handlerGetMe)
+	serveMux.Handle("GET /api/me/bookmarks", requireFeature(apiConfig.features.Bookmarks, apiConfig.handlerGetBookmarks))
+	serveMux.Handle("GET /api/me/timeline", requireFeature(apiConfig.features.Follows, apiConfig.handlerGetTimeline))
+	serveMux.Handle("GET /api/me/feed.json", requireFeature(apiConfig.features.Follows, apiConfig.handlerGetTimelineFeed))
+	serveMux.HandleFunc("POST /api/me/api_keys", apiConfig.handlerCreateAPIKey)
+	serveMux.HandleFunc("GET /api/me/api_keys", apiConfig.handlerGetAPIKeys)
+	serveMux.HandleFunc("DELETE /api/me/api_keys/{id}", apiConfig.handlerDeleteAPIKey)
+	serveMux.HandleFunc("GET /api/me/export.json", apiConfig.handlerExport)
+	serveMux.HandleFunc("GET /api/me/sessions", apiConfig.handlerGetSessions)
+	serveMux.HandleFunc("DELETE /api/me/sessions/{id}", apiConfig.handlerDeleteSession)
+	serveMux.Handle("POST /api/login", maxBytes(defaultBodyLimit)(apiConfig.rateLimitByIP(apiConfig.loginLimiter, apiConfig.handlerLogin)))
 	serveMux.HandleFunc("POST /api/refresh", apiConfig.handlerRefresh)
 	serveMux.HandleFunc("POST /api/revoke", apiConfig.handlerRevoke)
-	serveMux.HandleFunc("POST /api/polka/webhooks", apiConfig.handlerPolkaWebhook)
+	serveMux.Handle("POST /api/polka/webhooks", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerPolkaWebhook)))
 	serveMux.HandleFunc("GET /admin/metrics", apiConfig.handlerMetrics)
+	serveMux.HandleFunc("GET /admin/metrics.json", apiConfig.handlerMetricsJSON)
 	serveMux.HandleFunc("POST /admin/reset", apiConfig.handlerReset)
-
+	serveMux.HandleFunc("GET /admin/users", apiConfig.handlerListUsers)
+	serveMux.Handle("POST /admin/users", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerAdminCreateUser)))
+	serveMux.Handle("POST /admin/invite_codes", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerCreateInviteCode)))
+	serveMux.HandleFunc("POST /admin/users/{id}/suspend", apiConfig.handlerSuspendUser)
+	serveMux.HandleFunc("DELETE /admin/users/{id}/suspend", apiConfig.handlerUnsuspendUser)
+	serveMux.Handle("POST /admin/users/{id}/chirpy_red", maxBytes(defaultBodyLimit)(http.HandlerFunc(apiConfig.handlerSetChirpyRed)))
+	serveMux.HandleFunc("POST /admin/users/{id}/revoke_sessions", apiConfig.handlerRevokeUserSessions)
+	serveMux.HandleFunc("POST /admin/maintenance", apiConfig.handlerStartMaintenance)
+	serveMux.HandleFunc("DELETE /admin/maintenance", apiConfig.handlerStopMaintenance)
+	serveMux.HandleFunc("GET /admin/features", apiConfig.handlerGetFeatures)
+	serveMux.HandleFunc("DELETE /admin/chirps/{id}", apiConfig.handlerAdminDeleteChirp)
+	serveMux.HandleFunc(apiFallbackPattern, handlerAPIFallback(serveMux))
+
+	server.Handler = middlewareTracing(middlewareGzip(int(gzipMinSize), cors.middleware(apiConfig.middlewareMaintenance(apiConfig.middlewareCSRF(apiConfig.middlewareAdmin(serveMux))))))
 	err = server.ListenAndServe()
+	//flush the spans still buffered
+	shutdownTracing(context.Background())
 	if err != nil {
 		log.Fatalf("unable to listen and serve: %v", err)
 	}
 }
 
-func handlerReadiness(w http.ResponseWriter, req *http.Request) {
+// apiFallbackPattern catches /api requests no other pattern matched.
+const apiFallbackPattern = "/api/"
+
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// handlerAPIFallback answers unmatched /api requests with a JSON error like the
+// rest of the API. If the path exists under other methods it's a 405 with an
+// Allow header, otherwise a 404.
+func handlerAPIFallback(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var allowed []string
+		for _, method := range routeMethods {
+			probe := req.Clone(req.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != apiFallbackPattern {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) == 0 {
+			respondWithError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		respondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handlerLiveness only says the process is up. It never touches the
+// database, so a DB blip doesn't get a healthy pod restarted.
+func handlerLiveness(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
+type dbPinger interface {
+	PingContext(ctx context.Context) error
+}
+
+const readinessTimeout = 2 * time.Second
+
+func (a *apiConfig) handlerReadiness(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := a.pinger.PingContext(ctx); err != nil {
+		log.Printf("in handlerReadiness, unable to reach database: %v", err)
+		w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("database unavailable"))
+		return
+	}
+
+	w.Header().Add("Content-Type", a.healthz.contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(a.healthz.body))
+}
+
 func handlerApp(strip string, rootPath string) http.Handler {
 	return http.StripPrefix(strip, http.FileServer(http.Dir(rootPath)))
 }
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
-	dbQueries      *database.Queries
+	dbQueries      database.Querier
+	readQueries    database.Querier
+	runTx          txRunner
+	pinger         dbPinger
 	platform       string
-	secret         string
+	jwtKeys        auth.TokenKeys
+	jwtIssuer      string
 	polkaKey       string
+	adminToken     string
+	hashParams     *argon2id.Params
+	rehashOnLogin  bool
+	allowSignups   bool
+	inviteOnly     bool
+	refreshCookie  bool
+	maxSessions    int
+	profanity      profanity.Filter
+	maxChirps      int32
+	//private instance: reading chirps needs a token too
+	requireReadAuth bool
+	//refuse writes, toggled at runtime from /admin/maintenance
+	maintenance atomic.Bool
+	webhooks    *webhookDispatcher
+	healthz     healthzResponse
+	//how long an Idempotency-Key on chirp creation is remembered
+	idempotencyTTL time.Duration
+	//hosts attachment URLs may point at, any host when empty
+	attachmentHosts []string
+	minChirpLength  int
+	maxChirpLength  int
+	redChirpLength  int
+	editWindow      time.Duration
+	deleteWindow    time.Duration
+	defaultChirpTTL time.Duration
+	//how long the same body can't be posted again by its author, 0 for ever allowed
+	duplicateWindow time.Duration
+	trustedProxies  trustedProxies
+	loginLimiter    rateLimiter
+	chirpLimiter    rateLimiter
+	redChirpLimiter rateLimiter
+	rootRedirect    string
+	publicBaseURL   string
+	features        featureFlags
+	pageSizes       pageSizes
+	//how long a deleted account can be reactivated before it is purged
+	deletionGrace time.Duration
+}
+
+// healthzResponse is what readiness checks get back when all is well, for
+// load balancers that expect a particular payload.
+type healthzResponse struct {
+	body        string
+	contentType string
 }
 
 func (a *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -96,6 +546,134 @@ func (a *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 		})
 }
 
+// middlewareReadAuth lets anyone read unless REQUIRE_AUTH_FOR_READ is set,
+// in which case it takes the same valid token writes do.
+func (a *apiConfig) middlewareReadAuth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if a.requireReadAuth {
+				if _, err := a.authenticate(req); err != nil {
+					log.Printf("in middlewareReadAuth, unable to authenticate: %v", err)
+					respondWithAuthError(w, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+}
+
+// Request body limits for maxBytes. Chirps are short even with attachments,
+// everything else that takes JSON gets a bit more room.
+const (
+	chirpBodyLimit   = 16 << 10
+	defaultBodyLimit = 64 << 10
+)
+
+// maxBytes caps the request body at n bytes. A declared Content-Length over
+// the cap is refused up front, anything else is cut off while reading.
+func maxBytes(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, req *http.Request) {
+				if req.ContentLength > n {
+					respondWithError(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+					return
+				}
+				req.Body = http.MaxBytesReader(w, req.Body, n)
+				next.ServeHTTP(w, req)
+			})
+	}
+}
+
+var (
+	errAccountSuspended   = errors.New("account is suspended")
+	errAccountDeactivated = errors.New("account is deactivated")
+)
+
+// authenticate returns the user behind the request's access token. The user
+// is looked up on every request so a suspension takes effect immediately,
+// not when the token expires.
+func (a *apiConfig) authenticate(req *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	//no endpoint serves only one client type yet, so any audience will do
+	userID, err := auth.ValidateJWTUserID(token, a.jwtKeys, a.jwtIssuer, "")
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return a.activeUser(req.Context(), userID)
+}
+
+// authenticateWithAPIKey is authenticate for endpoints scripts may call,
+// which also take an "Authorization: ApiKey <key>" from /api/me/api_keys.
+func (a *apiConfig) authenticateWithAPIKey(req *http.Request) (uuid.UUID, error) {
+	key, err := auth.GetAPIKey(req.Header)
+	if err != nil {
+		return a.authenticate(req)
+	}
+
+	apiKey, err := a.dbQueries.GetAPIKeyByHash(req.Context(), auth.HashAPIKey(key))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return a.activeUser(req.Context(), apiKey.UserID)
+}
+
+// activeUser returns userID if the account can still be used.
+func (a *apiConfig) activeUser(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	dbUser, err := a.dbQueries.GetUser(ctx, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if dbUser.Suspended {
+		return uuid.Nil, errAccountSuspended
+	}
+	if dbUser.DeactivatedAt.Valid {
+		return uuid.Nil, errAccountDeactivated
+	}
+
+	return userID, nil
+}
+
+// clientAudiences maps the X-Client-Type hint sent at login and refresh to
+// the audience the access token is scoped to.
+var clientAudiences = map[string]string{
+	"web":    "web",
+	"mobile": "mobile",
+}
+
+// clientAudience reads the client hint. No hint means an unscoped token.
+func clientAudience(req *http.Request) (string, error) {
+	hint := req.Header.Get("X-Client-Type")
+	if hint == "" {
+		return "", nil
+	}
+
+	audience, ok := clientAudiences[hint]
+	if !ok {
+		return "", fmt.Errorf("unknown client type %q", hint)
+	}
+	return audience, nil
+}
+
+// respondWithAuthError answers a failed authenticate.
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAccountSuspended) {
+		respondWithError(w, http.StatusForbidden, "Account is suspended")
+		return
+	}
+	if errors.Is(err, errAccountDeactivated) {
+		respondWithError(w, http.StatusForbidden, "Account is deactivated")
+		return
+	}
+	respondWithDBError(w, http.StatusUnauthorized, err)
+}
+
 const metricsHtml = `<html>
   <body>
     <h1>Welcome, Chirpy Admin</h1>
@@ -111,189 +689,499 @@ func (a *apiConfig) handlerMetrics(w http.ResponseWriter, req *http.Request) {
 	w.Write([]byte(output))
 }
 
+// handlerMetricsJSON is handlerMetrics for monitoring tools.
+func (a *apiConfig) handlerMetricsJSON(w http.ResponseWriter, req *http.Request) {
+	type metrics struct {
+		FileserverHits int32 `json:"fileserver_hits"`
+	}
+	respondWithJSON(w, http.StatusOK, metrics{FileserverHits: a.fileserverHits.Load()})
+}
+
 func (a *apiConfig) handlerReset(w http.ResponseWriter, req *http.Request) {
-	w.Header().Add("Content-type", "text/plain; charset=utf-8")
 	if a.platform != "dev" {
-		w.WriteHeader(403)
+		log.Printf("in handlerReset, refused reset from %s: platform is %q", req.RemoteAddr, a.platform)
+		respondWithError(w, http.StatusForbidden, "Reset is only available in dev")
 		return
 	}
-	err := a.dbQueries.DeleteAllUsers(req.Context())
+
+	type summary struct {
+		Users  int64 `json:"users"`
+		Chirps int64 `json:"chirps"`
+		Hits   int32 `json:"hits"`
+	}
+	var deleted summary
+
+	err := a.runTx(req.Context(), func(q database.Querier) error {
+		var err error
+		//count chirps before the user delete cascades them away
+		deleted.Chirps, err = q.DeleteAllChirps(req.Context())
+		if err != nil {
+			return err
+		}
+		deleted.Users, err = q.DeleteAllUsers(req.Context())
+		return err
+	})
 	if err != nil {
-		log.Printf("in handlerReset, unable to delete users: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("in handlerReset, unable to reset database: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
+	deleted.Hits = a.fileserverHits.Swap(0)
 
-	w.WriteHeader(http.StatusOK)
-	a.fileserverHits.Swap(0)
+	log.Printf("reset at %s from %s: deleted %d users, %d chirps, %d hits",
+		time.Now().UTC().Format(time.RFC3339), req.RemoteAddr, deleted.Users, deleted.Chirps, deleted.Hits)
+	respondWithJSON(w, http.StatusOK, struct {
+		Deleted summary `json:"deleted"`
+	}{deleted})
 }
 
+// maxEmailLength is the RFC 5321 limit in octets, also enforced by the
+// users_email_length constraint.
+const maxEmailLength = 254
+
+// maxIdempotencyKeyLength bounds what we store per Idempotency-Key.
+const maxIdempotencyKeyLength = 255
+
 func (a *apiConfig) handlerUsers(w http.ResponseWriter, req *http.Request) {
+	//Closed instances only get users from an admin
+	if !a.allowSignups {
+		respondWithError(w, http.StatusForbidden, "Signups are closed")
+		return
+	}
+
 	//get JSON
 	type parameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		InviteCode string `json:"invite_code"`
 	}
 
 	var params parameters
-	decoder := json.NewDecoder(req.Body)
+	decoder := newJSONDecoder(req.Body)
 	if err := decoder.Decode(&params); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
 		log.Printf("in handlerUsers, unable to decode JSON: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if params.Password == "" {
-		log.Printf("in handlerUsers, empty password")
-		w.WriteHeader(http.StatusInternalServerError)
+	//Invite-only instances need a code, which the signup uses up
+	var inviteCode string
+	if a.inviteOnly {
+		if params.InviteCode == "" {
+			respondWithError(w, http.StatusForbidden, "An invite code is required")
+			return
+		}
+		inviteCode = params.InviteCode
+	}
+
+	dbUser, ok := a.createUser(w, req, params.Email, params.Password, inviteCode)
+	if !ok {
 		return
 	}
 
+	user := userFromDB(dbUser)
+	jsonDat, err := json.Marshal(user)
+	if err != nil {
+		log.Printf("in handlerUsers, unable to encode JSON response: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	w.Write(jsonDat)
+}
+
+// createUser validates the credentials and adds the user, for signups and
+// admins alike. A non-empty inviteCode is used up in the same transaction,
+// so a failed signup doesn't spend it. On failure it has already responded.
+func (a *apiConfig) createUser(w http.ResponseWriter, req *http.Request, email, password, inviteCode string) (database.User, bool) {
+	if errs := validateCredentials(email, password); len(errs) > 0 {
+		log.Printf("in createUser, %d invalid fields", len(errs))
+		respondWithFieldErrors(w, errs)
+		return database.User{}, false
+	}
+
 	//hash password
-	hashed_password, err := auth.HashPassword(params.Password)
+	hashed_password, err := auth.HashPassword(password, a.hashParams)
 	if err != nil {
-		log.Printf("in handlerUsers, unable to hash password: %v", err)
+		log.Printf("in createUser, unable to hash password: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return database.User{}, false
 	}
 
 	//write to database
 	createUserArgs := database.CreateUserParams{
-		Email:          params.Email,
+		Email:          email,
 		HashedPassword: hashed_password,
 	}
-	dbUser, err := a.dbQueries.CreateUser(req.Context(), createUserArgs)
+	var dbUser database.User
+	err = a.runTx(req.Context(), func(q database.Querier) error {
+		if inviteCode != "" {
+			if _, err := q.UseInviteCode(req.Context(), inviteCode); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		dbUser, err = q.CreateUser(req.Context(), createUserArgs)
+		if err != nil || inviteCode == "" {
+			return err
+		}
+
+		useArgs := database.CreateInviteCodeUseParams{
+			Code:   inviteCode,
+			UserID: dbUser.ID,
+		}
+		return q.CreateInviteCodeUse(req.Context(), useArgs)
+	})
 	if err != nil {
-		log.Printf("in handlerUsers, unable to add to database: %v", err)
-		w.WriteHeader(400)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusForbidden, "Invalid or expired invite code")
+			return database.User{}, false
+		}
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "email already registered")
+			return database.User{}, false
+		}
+		log.Printf("in createUser, unable to add to database: %v", err)
+		respondWithDBError(w, 400, err)
+		return database.User{}, false
+	}
+	return dbUser, true
+}
+
+func (a *apiConfig) handlerPutUsers(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerPutUsers, uanble to authenticate user: %v", err)
+		respondWithAuthError(w, err)
 		return
 	}
 
-	// user := User(dbUser)
-	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChripyRed: dbUser.IsChirpyRed,
+	//decode request body
+	type reqBody struct {
+		Password string `json:"password"`
+		Email    string `json:"email"`
 	}
-	jsonDat, err := json.Marshal(user)
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerPutUsers, unable to decode request body: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	if errs := validateCredentials(body.Email, body.Password); len(errs) > 0 {
+		log.Printf("in handlerPutUsers, %d invalid fields", len(errs))
+		respondWithFieldErrors(w, errs)
+		return
+	}
+
+	//hash password
+	hashedPassword, err := auth.HashPassword(body.Password, a.hashParams)
 	if err != nil {
-		log.Printf("in handlerUsers, unable to encode JSON response: %v", err)
-		w.WriteHeader(400)
+		log.Printf("in handlerPutUsers, unable to hash password: %v", err)
+		w.WriteHeader(401)
 		return
 	}
 
+	//update
+	updateArgs := database.UpdateUserEmailAndPassParams{
+		ID:             userID,
+		Email:          body.Email,
+		HashedPassword: hashedPassword,
+	}
+	user, err := a.dbQueries.UpdateUserEmailAndPass(req.Context(), updateArgs)
+	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "email already registered")
+			return
+		}
+		log.Printf("in handlerPutUsers, unable to update email and password: %v", err)
+		respondWithDBError(w, 401, err)
+		return
+	}
+
+	//success
+	resUser := userFromDB(user)
+	jsonDat, err := json.Marshal(resUser)
+	if err != nil {
+		log.Printf("in handlerPutUsers, unable to encode response: %v", err)
+		w.WriteHeader(401)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(201)
+	w.WriteHeader(200)
 	w.Write(jsonDat)
 }
 
-func (a *apiConfig) handlerPutUsers(w http.ResponseWriter, req *http.Request) {
-	//Check access token
-	accessToken, err := auth.GetBearerToken(req.Header)
+func (a *apiConfig) handlerGetMe(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
 	if err != nil {
-		log.Printf("in handlerPutUsers, unable to get access token: %v", err)
-		w.WriteHeader(401)
+		log.Printf("in handlerGetMe, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
 		return
 	}
 
+	dbUser, err := a.dbQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetMe, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, userFromDB(dbUser))
+}
+
+func (a *apiConfig) handlerChangePassword(w http.ResponseWriter, req *http.Request) {
 	//Authenticate
-	userID, err := auth.ValidateJWT(accessToken, a.secret)
+	userID, err := a.authenticate(req)
 	if err != nil {
-		log.Printf("in handlerPutUsers, uanble to authenticate user: %v", err)
-		w.WriteHeader(401)
+		log.Printf("in handlerChangePassword, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
 		return
 	}
 
 	//decode request body
 	type reqBody struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
 	}
 
 	var body reqBody
-	decoder := json.NewDecoder(req.Body)
+	decoder := newJSONDecoder(req.Body)
 	if err := decoder.Decode(&body); err != nil {
-		log.Printf("in handlerPutUsers, unable to decode request body: %v", err)
-		w.WriteHeader(401)
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerChangePassword, unable to decode request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if body.NewPassword == "" {
+		respondWithError(w, http.StatusBadRequest, "New password is required")
+		return
+	}
+
+	//verify the old password before touching anything
+	dbUser, err := a.dbQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerChangePassword, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	match, err := auth.CheckPassword(body.OldPassword, dbUser.HashedPassword)
+	if err != nil || !match {
+		log.Printf("in handlerChangePassword, old password did not match: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password")
+		return
+	}
+
+	//hash and store the new one
+	hashedPassword, err := auth.HashPassword(body.NewPassword, a.hashParams)
+	if err != nil {
+		log.Printf("in handlerChangePassword, unable to hash password: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	updateArgs := database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	}
+	err = a.dbQueries.UpdateUserPassword(req.Context(), updateArgs)
+	if err != nil {
+		log.Printf("in handlerChangePassword, unable to update password: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiConfig) handlerGetUser(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerGetUser, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dbUser, err := a.readQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetUser, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+	if dbUser.DeactivatedAt.Valid {
+		log.Printf("in handlerGetUser, deactivated user: %s", userID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//social graph counts
+	followers, err := a.readQueries.CountFollowers(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetUser, unable to count followers: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	following, err := a.readQueries.CountFollowing(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetUser, unable to count following: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	profile := UserProfile{
+		ID:             dbUser.ID,
+		CreatedAt:      dbUser.CreatedAt,
+		IsChirpyRed:    dbUser.IsChirpyRed,
+		FollowerCount:  followers,
+		FollowingCount: following,
+	}
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+// maxBatchUsers caps how many profiles handlerGetUsersBatch returns at once.
+const maxBatchUsers = 100
+
+func (a *apiConfig) handlerGetUsersBatch(w http.ResponseWriter, req *http.Request) {
+	type reqBody struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerGetUsersBatch, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Something went wrong")
 		return
 	}
 
-	//hash password
-	hashedPassword, err := auth.HashPassword(body.Password)
-	if err != nil {
-		log.Printf("in handlerPutUsers, unable to hash password: %v", err)
-		w.WriteHeader(401)
+	if len(body.IDs) > maxBatchUsers {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", maxBatchUsers))
 		return
 	}
 
-	//update
-	updateArgs := database.UpdateUserEmailAndPassParams{
-		ID:             userID,
-		Email:          body.Email,
-		HashedPassword: hashedPassword,
-	}
-	user, err := a.dbQueries.UpdateUserEmailAndPass(req.Context(), updateArgs)
-	if err != nil {
-		log.Printf("in handlerPutUsers, unable to update email and password: %v", err)
-		w.WriteHeader(401)
+	//unknown ids are left out rather than failing the whole batch
+	profiles := []UserProfile{}
+	if len(body.IDs) == 0 {
+		respondWithJSON(w, http.StatusOK, profiles)
 		return
 	}
 
-	//success
-	resUser := User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChripyRed: user.IsChirpyRed,
-	}
-	jsonDat, err := json.Marshal(resUser)
+	rows, err := a.readQueries.GetUsersByIDs(req.Context(), body.IDs)
 	if err != nil {
-		log.Printf("in handlerPutUsers, unable to encode response: %v", err)
-		w.WriteHeader(401)
+		log.Printf("in handlerGetUsersBatch, unable to get users: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	w.Write(jsonDat)
+
+	for _, row := range rows {
+		profiles = append(profiles, UserProfile{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			IsChirpyRed:    row.IsChirpyRed,
+			FollowerCount:  row.FollowerCount,
+			FollowingCount: row.FollowingCount,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, profiles)
 }
 
 func (a *apiConfig) handlerGetChirps(w http.ResponseWriter, req *http.Request) {
-	//check request for author_id
-	var dbChirps []database.Chirp
+	listArgs := database.ListChirpsParams{}
+
+	//check request for author_id, otherwise get all chirps
 	authorIDStr := req.URL.Query().Get("author_id")
 	authorID, err := uuid.Parse(authorIDStr)
-	if err != nil {
-		// just get all chirps
-		dbChirps, err = a.dbQueries.GetAllChirps(req.Context())
+	if err == nil {
+		listArgs.AuthorID = uuid.NullUUID{UUID: authorID, Valid: true}
+	}
+
+	//only chirps from Chirpy Red authors?
+	listArgs.AuthorRed = req.URL.Query().Get("author_red") == "true"
+
+	// check sort query parameter
+	sortStr := req.URL.Query().Get("sort")
+	listArgs.NewestFirst = sortStr == "desc"
+
+	//never hand back the whole table
+	listArgs.MaxResults = a.maxChirps
+
+	//only what the reader is allowed to see
+	listArgs.ViewerID = a.viewer(req)
+
+	//only chirps detected as one language?
+	if langStr := req.URL.Query().Get("lang"); langStr != "" {
+		listArgs.Lang = sql.NullString{String: langStr, Valid: true}
+	}
+
+	//polling: only what came after the last chirp the client saw
+	if sinceIDStr := req.URL.Query().Get("since_id"); sinceIDStr != "" {
+		sinceID, err := uuid.Parse(sinceIDStr)
 		if err != nil {
-			log.Printf("in handlerGetChirps, unable to get all chirps: %v", err)
-			w.WriteHeader(501)
+			respondWithError(w, http.StatusBadRequest, "Invalid since_id")
 			return
 		}
-	} else {
-		//get the chirps for only the author
-		dbChirps, err = a.dbQueries.GetChirpsByAuthor(req.Context(), authorID)
-		if err != nil {
-			log.Printf("in handlerGetChirps, unable to get chirps by author: %v", err)
-			w.WriteHeader(501)
+		since, err := a.readQueries.GetChirp(req.Context(), sinceID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("in handlerGetChirps, unable to get since chirp: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+		visible := false
+		if err == nil {
+			visible, err = canView(req.Context(), a.readQueries, listArgs.ViewerID, since)
+			if err != nil {
+				log.Printf("in handlerGetChirps, unable to check since chirp: %v", err)
+				respondWithDBError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		if !visible {
+			respondWithError(w, http.StatusBadRequest, "since_id does not exist")
 			return
 		}
+		listArgs.SinceCreatedAt = sql.NullTime{Time: since.CreatedAt, Valid: true}
+		listArgs.SinceID = uuid.NullUUID{UUID: since.ID, Valid: true}
+		listArgs.NewestFirst = false
+		listArgs.MaxResults = min(a.pageSizes.limit(req.URL.Query()), a.maxChirps)
+		setPageSize(w, listArgs.MaxResults)
 	}
 
-	// check sort query parameter
-	sortStr := req.URL.Query().Get("sort")
-	if sortStr == "desc" {
-		sort.Slice(dbChirps, func(i, j int) bool {
-			return dbChirps[i].CreatedAt.After(dbChirps[j].CreatedAt)
-		})
+	dbChirps, err := a.readQueries.ListChirps(req.Context(), listArgs)
+	if err != nil {
+		log.Printf("in handlerGetChirps, unable to list chirps: %v", err)
+		respondWithDBError(w, 501, err)
+		return
+	}
+	if len(dbChirps) == int(a.maxChirps) && !listArgs.SinceID.Valid {
+		log.Printf("in handlerGetChirps, response capped at %d chirps", a.maxChirps)
 	}
 
-	chirps := []Chirp{}
-	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp(dbChirp))
+	chirps, err := chirpsFromDB(req.Context(), a.readQueries, listArgs.ViewerID, dbChirps)
+	if err != nil {
+		log.Printf("in handlerGetChirps, unable to load chirps: %v", err)
+		respondWithDBError(w, 501, err)
+		return
 	}
 
 	jsonDat, err := json.Marshal(chirps)
@@ -310,19 +1198,10 @@ func (a *apiConfig) handlerGetChirps(w http.ResponseWriter, req *http.Request) {
 
 func (a *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request) {
 	//authenticate
-	//Get bearer token
-	accessToken, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		log.Printf("in handlerDeleteChirp, unable to get bearer token: %v", err)
-		w.WriteHeader(401)
-		return
-	}
-
-	//validate
-	userID, err := auth.ValidateJWT(accessToken, a.secret)
+	userID, err := a.authenticate(req)
 	if err != nil {
 		log.Printf("in handlerDeleteChirp, unable to validate: %v", err)
-		w.WriteHeader(401)
+		respondWithAuthError(w, err)
 		return
 	}
 
@@ -344,7 +1223,7 @@ func (a *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request)
 	chirp, err := a.dbQueries.GetChirp(req.Context(), chirpID)
 	if err != nil {
 		log.Printf("in handlerDeleteChirp, could not get chirp: %v", err)
-		w.WriteHeader(404)
+		respondWithDBError(w, 404, err)
 		return
 	}
 
@@ -354,11 +1233,17 @@ func (a *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	//admins can still remove it with DELETE /admin/chirps/{id}
+	if !withinWindow(chirp.CreatedAt, a.deleteWindow, time.Now()) {
+		respondWithError(w, http.StatusForbidden, "Chirp can no longer be deleted")
+		return
+	}
+
 	//Delete finally
 	err = a.dbQueries.DeleteChirp(req.Context(), chirpID)
 	if err != nil {
 		log.Printf("in handlerDeleteChirp, unable to delete chirp: %v", err)
-		w.WriteHeader(404)
+		respondWithDBError(w, 404, err)
 		return
 	}
 
@@ -370,8 +1255,12 @@ func (a *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request)
 func (a *apiConfig) handlerChirps(w http.ResponseWriter, req *http.Request) {
 
 	type chirpRequest struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body          string       `json:"body"`
+		UserID        uuid.UUID    `json:"user_id"`
+		Attachments   []Attachment `json:"attachments"`
+		QuotedChirpID *uuid.UUID   `json:"quoted_chirp_id"`
+		Visibility    string       `json:"visibility"`
+		ExpiresIn     string       `json:"expires_in"`
 	}
 
 	type errorResponse struct {
@@ -381,8 +1270,11 @@ func (a *apiConfig) handlerChirps(w http.ResponseWriter, req *http.Request) {
 	// Receive from client
 	w.Header().Set("Content-Type", "application/json")
 	var chirp chirpRequest
-	decoder := json.NewDecoder(req.Body)
+	decoder := newJSONDecoder(req.Body)
 	if err := decoder.Decode(&chirp); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
 		log.Printf("while validating chirp: something went wrong: %v", err)
 		errResp := errorResponse{Error: "Something went wrong"}
 		respData, err := json.Marshal(errResp)
@@ -395,20 +1287,43 @@ func (a *apiConfig) handlerChirps(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	//Authenticate
-	token, err := auth.GetBearerToken(req.Header)
+	//Authenticate, scripts may use an API key
+	userID, err := a.authenticateWithAPIKey(req)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("in handlerChirps, unable to get bearer token: %v", err)
+		log.Printf("in handlerChirps, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, a.secret)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("in handlerChirps, unable to validate jwt: %v", err)
+	//A retry with the same key gets the original chirp back
+	idempotencyKey := req.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		respondWithError(w, http.StatusBadRequest, "Idempotency-Key is too long")
 		return
 	}
+	if idempotencyKey != "" {
+		idempotentArgs := database.GetIdempotentChirpParams{
+			UserID:    userID,
+			Key:       idempotencyKey,
+			NotBefore: time.Now().UTC().Add(-a.idempotencyTTL),
+		}
+		dbChirp, err := a.dbQueries.GetIdempotentChirp(req.Context(), idempotentArgs)
+		if err == nil {
+			chirps, err := chirpsFromDB(req.Context(), a.dbQueries, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{dbChirp})
+			if err != nil {
+				log.Printf("in handlerChirps, unable to load chirp: %v", err)
+				respondWithDBError(w, http.StatusInternalServerError, err)
+				return
+			}
+			respondWithJSON(w, http.StatusOK, chirps[0])
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("in handlerChirps, unable to check idempotency key: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
 	// if userID != chirp.UserID {
 	// 	w.WriteHeader(http.StatusUnauthorized)
@@ -416,81 +1331,190 @@ func (a *apiConfig) handlerChirps(w http.ResponseWriter, req *http.Request) {
 	// 	return
 	// }
 
-	// Check Length
-	if len(chirp.Body) > 140 {
-		w.WriteHeader(400)
-		log.Printf("chirp is too long")
-		errResp := errorResponse{Error: "Chirp is too long"}
-		respData, err := json.Marshal(errResp)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Printf("while responding chirp to long: %v", err)
-			respData = []byte{}
-		}
-		w.Write(respData)
+	//The author's tier sets their limits
+	author, err := a.dbQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerChirps, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	//Rate limit per user, Chirpy Red has its own (usually no) limit
+	limiter := a.chirpLimiter
+	if author.IsChirpyRed {
+		limiter = a.redChirpLimiter
+	}
+	if ok, retryAfter := limiter.allow(req.Context(), userID.String()); !ok {
+		log.Printf("in handlerChirps, rate limited user %s", userID)
+		respondWithRateLimited(w, retryAfter)
 		return
 	}
 
-	//Check for forbidden words
-	badWords := []string{"kerfuffle", "sharbert", "fornax"}
-	chirpWords := strings.Fields(chirp.Body)
-	cleanedWords := []string{}
-	cleaned := false
+	// Check length and forbidden words, ignoring surrounding whitespace, which isn't stored
+	var ok bool
+	chirp.Body, ok = a.validateAndCleanChirp(w, chirp.Body, author.IsChirpyRed)
+	if !ok {
+		return
+	}
 
-	for _, word := range chirpWords {
-		if slices.Contains(badWords, strings.ToLower(word)) {
-			cleanedWords = append(cleanedWords, "****")
-			cleaned = true
-		} else {
-			cleanedWords = append(cleanedWords, word)
+	//Copy-paste spam, the same body again too soon
+	if a.duplicateWindow > 0 {
+		duplicateArgs := database.HasRecentDuplicateChirpParams{
+			UserID:    userID,
+			Body:      chirp.Body,
+			NotBefore: time.Now().UTC().Add(-a.duplicateWindow),
+		}
+		duplicate, err := a.dbQueries.HasRecentDuplicateChirp(req.Context(), duplicateArgs)
+		if err != nil {
+			log.Printf("in handlerChirps, unable to check for duplicates: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if duplicate {
+			respondWithError(w, http.StatusConflict, "You posted the same chirp recently")
+			return
 		}
 	}
 
-	rebuilt := strings.Join(cleanedWords, " ")
+	if err := validateAttachments(chirp.Attachments, a.attachmentHosts); err != nil {
+		log.Printf("in handlerChirps, invalid attachments: %v", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	type cleanedResponse struct {
-		CleanedBody string `json:"cleaned_body"`
+	//Public unless asked otherwise
+	visibility := database.ChirpVisibilityPublic
+	if chirp.Visibility != "" {
+		v, ok := chirpVisibilities[chirp.Visibility]
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, "Invalid visibility")
+			return
+		}
+		visibility = v
 	}
 
-	if cleaned {
-		log.Printf("cleaned chirp")
-		cleanedBody := cleanedResponse{CleanedBody: rebuilt}
-		respData, err := json.Marshal(cleanedBody)
-		if err != nil {
-			log.Printf("while responding with cleaned chirp: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			respData = []byte{}
+	//Lives forever unless asked otherwise or there's a default TTL
+	ttl := a.defaultChirpTTL
+	if chirp.ExpiresIn != "" {
+		d, err := time.ParseDuration(chirp.ExpiresIn)
+		if err != nil || d <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid expires_in")
+			return
 		}
-		w.WriteHeader(403)
-		w.Write(respData)
+		ttl = d
+	}
+	expiresAt := sql.NullTime{}
+	if ttl > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().UTC().Add(ttl), Valid: true}
+	}
+
+	//Can only quote a chirp that exists and the author can see
+	quotedChirpID := uuid.NullUUID{}
+	if chirp.QuotedChirpID != nil && !a.features.Quotes {
+		respondWithError(w, http.StatusBadRequest, "Quoting is disabled")
 		return
 	}
+	if chirp.QuotedChirpID != nil {
+		quoted, err := a.dbQueries.GetChirp(req.Context(), *chirp.QuotedChirpID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("in handlerChirps, unable to get quoted chirp: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+		visible := false
+		if err == nil {
+			visible, err = canView(req.Context(), a.dbQueries, uuid.NullUUID{UUID: userID, Valid: true}, quoted)
+			if err != nil {
+				log.Printf("in handlerChirps, unable to check quoted chirp: %v", err)
+				respondWithDBError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		if !visible {
+			respondWithError(w, http.StatusBadRequest, "Quoted chirp does not exist")
+			return
+		}
+		quotedChirpID = uuid.NullUUID{UUID: *chirp.QuotedChirpID, Valid: true}
+	}
 
 	//All is well
 	log.Printf("chirp valid")
 
-	// call database to save chirp
+	// call database to save chirp, the creation is its first revision
 	createChirpParams := database.CreateChirpParams{
 		Body: chirp.Body,
 		// UserID: chirp.UserID,
-		UserID: userID,
-	}
-	dbChirp, err := a.dbQueries.CreateChirp(req.Context(), createChirpParams)
+		UserID:        userID,
+		QuotedChirpID: quotedChirpID,
+		Visibility:    visibility,
+		Lang:          lang.Detect(chirp.Body),
+		ExpiresAt:     expiresAt,
+	}
+	var dbChirp database.Chirp
+	err = a.runTx(req.Context(), func(q database.Querier) error {
+		var err error
+		dbChirp, err = q.CreateChirp(req.Context(), createChirpParams)
+		if err != nil {
+			return err
+		}
+
+		revisionArgs := database.CreateChirpRevisionParams{
+			ChirpID: dbChirp.ID,
+			Body:    dbChirp.Body,
+		}
+		err = q.CreateChirpRevision(req.Context(), revisionArgs)
+		if err != nil {
+			return err
+		}
+
+		for i, attachment := range chirp.Attachments {
+			attachmentArgs := database.CreateChirpAttachmentParams{
+				ChirpID:  dbChirp.ID,
+				Position: int32(i),
+				Url:      attachment.URL,
+				Type:     attachment.Type,
+			}
+			err = q.CreateChirpAttachment(req.Context(), attachmentArgs)
+			if err != nil {
+				return err
+			}
+		}
+
+		if idempotencyKey != "" {
+			keyArgs := database.CreateIdempotencyKeyParams{
+				UserID:  userID,
+				Key:     idempotencyKey,
+				ChirpID: dbChirp.ID,
+			}
+			return q.CreateIdempotencyKey(req.Context(), keyArgs)
+		}
+		return nil
+	})
 	if err != nil {
 		log.Printf("in handlerChirps, unable to create chirp: %v", err)
 		log.Printf("chirp: %v", chirp)
 		log.Printf("createChirpParams:%v", createChirpParams)
-		w.WriteHeader(501)
+		respondWithDBError(w, 501, err)
+		return
+	}
+
+	chirps, err := chirpsFromDB(req.Context(), a.dbQueries, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{dbChirp})
+	if err != nil {
+		log.Printf("in handlerChirps, unable to load chirp: %v", err)
+		respondWithDBError(w, 501, err)
 		return
 	}
 
-	response := Chirp(dbChirp)
-	jsonDat, err := json.Marshal(response)
+	jsonDat, err := json.Marshal(chirps[0])
 	if err != nil {
 		log.Printf("in handlerChirps, unable to encode response: %v", err)
 		w.WriteHeader(501)
 		return
 	}
+	//the webhook is outside the visibility rules, so only public chirps go to it
+	if dbChirp.Visibility == database.ChirpVisibilityPublic {
+		a.webhooks.send("chirp.created", chirps[0])
+	}
 
 	w.WriteHeader(201)
 	w.Write(jsonDat)
@@ -512,15 +1536,34 @@ func (a *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	dbChirp, err := a.dbQueries.GetChirp(req.Context(), id)
+	dbChirp, err := a.readQueries.GetChirp(req.Context(), id)
 	if err != nil {
 		log.Printf("in handlerChirps, unable to get chirp: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+
+	//a chirp the reader can't see doesn't exist as far as they know
+	viewer := a.viewer(req)
+	visible, err := canView(req.Context(), a.readQueries, viewer, dbChirp)
+	if err != nil {
+		log.Printf("in handlerGetChirp, unable to check visibility: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !visible {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	chirp := Chirp(dbChirp)
-	jsonDat, err := json.Marshal(chirp)
+	chirps, err := chirpsFromDB(req.Context(), a.readQueries, viewer, []database.Chirp{dbChirp})
+	if err != nil {
+		log.Printf("in handlerGetChirp, unable to load chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	jsonDat, err := json.Marshal(chirps[0])
 	if err != nil {
 		log.Printf("unable to encode JSON: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -532,26 +1575,110 @@ func (a *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request) {
 	w.Write(jsonDat)
 }
 
+const maxBatchChirps = 100
+
+// handlerGetChirpsBatch hydrates chirps for clients that keep their own list
+// of ids. They come back in the order asked for.
+func (a *apiConfig) handlerGetChirpsBatch(w http.ResponseWriter, req *http.Request) {
+	type reqBody struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	var body reqBody
+	decoder := newJSONDecoder(req.Body)
+	if err := decoder.Decode(&body); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
+		log.Printf("in handlerGetChirpsBatch, unable to decode request body: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Something went wrong")
+		return
+	}
+
+	if len(body.IDs) > maxBatchChirps {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", maxBatchChirps))
+		return
+	}
+
+	//unknown and hidden ids are left out rather than failing the whole batch
+	if len(body.IDs) == 0 {
+		respondWithJSON(w, http.StatusOK, []Chirp{})
+		return
+	}
+
+	dbChirps, err := a.readQueries.GetChirpsByIDs(req.Context(), body.IDs)
+	if err != nil {
+		log.Printf("in handlerGetChirpsBatch, unable to get chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	viewer := a.viewer(req)
+	byID := map[uuid.UUID]database.Chirp{}
+	for _, dbChirp := range dbChirps {
+		visible, err := canView(req.Context(), a.readQueries, viewer, dbChirp)
+		if err != nil {
+			log.Printf("in handlerGetChirpsBatch, unable to check visibility: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if visible {
+			byID[dbChirp.ID] = dbChirp
+		}
+	}
+
+	ordered := []database.Chirp{}
+	for _, id := range body.IDs {
+		if dbChirp, ok := byID[id]; ok {
+			ordered = append(ordered, dbChirp)
+			//a repeated id comes back once
+			delete(byID, id)
+		}
+	}
+
+	chirps, err := chirpsFromDB(req.Context(), a.readQueries, viewer, ordered)
+	if err != nil {
+		log.Printf("in handlerGetChirpsBatch, unable to load chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
 func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	//request
 	type loginRequest struct {
 		Password         string `json:"password"`
+		Login            string `json:"login"`
 		Email            string `json:"email"`
 		ExpiresInSeconds int    `json:"expires_in_seconds,omitempty"`
 	}
 
 	var loginReq loginRequest
-	decoder := json.NewDecoder(req.Body)
+	decoder := newJSONDecoder(req.Body)
 	if err := decoder.Decode(&loginReq); err != nil {
+		if respondWithUnknownField(w, err) {
+			return
+		}
 		log.Printf("in handlerLogin, unable to decode JSON: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	//login is the new name for email; there are no usernames to try yet
+	login := loginReq.Login
+	if login == "" {
+		login = loginReq.Email
+	}
+
 	//query DB
-	dbUser, err := a.dbQueries.GetUserByEmail(req.Context(), loginReq.Email)
+	dbUser, err := a.dbQueries.GetUserByEmail(req.Context(), login)
 	if err != nil {
 		log.Printf("in handlerLogin, unable to find user by email: %v", err)
+		if isDBTimeout(err) {
+			respondWithDBError(w, http.StatusServiceUnavailable, err)
+			return
+		}
 		w.WriteHeader(http.StatusUnauthorized)
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte("Incorrect email or password"))
@@ -575,6 +1702,23 @@ func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//Bring the hash up to the current params while we have the password
+	if a.rehashOnLogin {
+		a.rehashPassword(req.Context(), dbUser, loginReq.Password)
+	}
+
+	//Only after the password check, so suspension doesn't reveal the account exists
+	if dbUser.Suspended {
+		log.Printf("in handlerLogin, suspended user: %s", dbUser.ID)
+		respondWithError(w, http.StatusForbidden, "Account is suspended")
+		return
+	}
+	if dbUser.DeactivatedAt.Valid {
+		log.Printf("in handlerLogin, deactivated user: %s", dbUser.ID)
+		respondWithError(w, http.StatusForbidden, "Account is deactivated")
+		return
+	}
+
 	//Generate a token
 	// expires_in_seconds := 1 * 60 * 60
 	// if loginReq.ExpiresInSeconds > 0 && loginReq.ExpiresInSeconds < 60*60 {
@@ -583,7 +1727,13 @@ func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 
 	// duration := time.Duration(expires_in_seconds) * time.Second
 	// log.Printf("in handlerLogin, duration: %v", duration)
-	token, err := auth.MakeJWT(dbUser.ID, a.secret, 1*time.Hour)
+	audience, err := clientAudience(req)
+	if err != nil {
+		log.Printf("in handlerLogin, bad client hint: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Unknown client type")
+		return
+	}
+	token, err := auth.MakeJWT(dbUser.ID, dbUser.IsChirpyRed, a.jwtKeys, a.jwtIssuer, 1*time.Hour, audience)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -597,15 +1747,23 @@ func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Add to DB
+	// Add to DB, making room for it if the user is at the session cap
 	refreshTokenArgs := database.CreateRefreshTokenParams{
-		Token:  refreshToken,
+		Token:  auth.HashRefreshToken(refreshToken),
 		UserID: dbUser.ID,
 	}
-	_, err = a.dbQueries.CreateRefreshToken(req.Context(), refreshTokenArgs)
+	err = a.runTx(req.Context(), func(q database.Querier) error {
+		if a.maxSessions > 0 {
+			if err := revokeOldestSessions(req.Context(), q, dbUser.ID, a.maxSessions-1); err != nil {
+				return err
+			}
+		}
+		_, err := q.CreateRefreshToken(req.Context(), refreshTokenArgs)
+		return err
+	})
 	if err != nil {
 		log.Printf("in handlerLogin, unable to create refresh token: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -616,14 +1774,21 @@ func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	// 	UpdatedAt: dbUser.UpdatedAt,
 	// 	ID:        dbUser.ID,
 	// }
+	//Bookkeeping only, a failure here shouldn't deny a valid login
+	err = a.dbQueries.UpdateUserLastLogin(req.Context(), dbUser.ID)
+	if err != nil {
+		log.Printf("in handlerLogin, unable to update last login: %v", err)
+	}
+
 	type userReturn struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		ID           uuid.UUID  `json:"id"`
+		CreatedAt    time.Time  `json:"created_at"`
+		UpdatedAt    time.Time  `json:"updated_at"`
+		Email        string     `json:"email"`
+		Token        string     `json:"token"`
+		RefreshToken string     `json:"refresh_token,omitempty"`
+		IsChirpyRed  bool       `json:"is_chirpy_red"`
+		LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
 	}
 	user := userReturn{
 		ID:           dbUser.ID,
@@ -633,7 +1798,22 @@ func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		Token:        token,
 		RefreshToken: refreshToken,
 		IsChirpyRed:  dbUser.IsChirpyRed,
+		LastLoginAt:  nullTime(dbUser.LastLoginAt),
+	}
+	//Browser clients get the refresh token as an HttpOnly cookie instead,
+	//along with a CSRF token their scripts echo back
+	if a.refreshCookie {
+		csrfToken, err := auth.MakeCSRFToken()
+		if err != nil {
+			log.Printf("in handlerLogin, unable to make CSRF token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		setRefreshTokenCookie(w, refreshToken)
+		setCSRFTokenCookie(w, csrfToken)
+		user.RefreshToken = ""
 	}
+
 	jsonDat, err := json.Marshal(&user)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -645,20 +1825,65 @@ func (a *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	w.Write(jsonDat)
 }
 
+// revokeOldestSessions revokes the user's oldest active refresh tokens
+// until at most keep are left.
+func revokeOldestSessions(ctx context.Context, q database.Querier, userID uuid.UUID, keep int) error {
+	//newest first
+	records, err := q.GetActiveRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for len(records) > keep {
+		oldest := records[len(records)-1]
+		if err := q.RevokeRefreshToken(ctx, oldest.Token); err != nil {
+			return err
+		}
+		log.Printf("revoked session %s of user %s, over the session cap", sessionID(oldest.Token), userID)
+		records = records[:len(records)-1]
+	}
+	return nil
+}
+
+// rehashPassword rehashes password with the current argon2id params if
+// dbUser's hash used others. It is bookkeeping for handlerLogin, so errors
+// are only logged, and the old hash keeps working until the next try.
+func (a *apiConfig) rehashPassword(ctx context.Context, dbUser database.User, password string) {
+	rehash, err := auth.NeedsRehash(dbUser.HashedPassword, a.hashParams)
+	if err != nil || !rehash {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(password, a.hashParams)
+	if err != nil {
+		log.Printf("in rehashPassword, unable to hash password: %v", err)
+		return
+	}
+
+	passwordArgs := database.UpdateUserPasswordParams{
+		ID:             dbUser.ID,
+		HashedPassword: hashedPassword,
+	}
+	if err := a.dbQueries.UpdateUserPassword(ctx, passwordArgs); err != nil {
+		log.Printf("in rehashPassword, unable to update password: %v", err)
+		return
+	}
+	log.Printf("rehashed password for user %s", dbUser.ID)
+}
+
 func (a *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
-	//Check for Refresh Token in headers
-	token, err := auth.GetBearerToken(req.Header)
+	//Check for Refresh Token in cookie or headers
+	token, err := getRefreshToken(req)
 	if err != nil {
-		log.Printf("in handlerRefresh, unable to get bearer token: %v", err)
+		log.Printf("in handlerRefresh, unable to get refresh token: %v", err)
 		w.WriteHeader(401)
 		return
 	}
 
 	//Is it legit?
-	dbTokenRecord, err := a.dbQueries.GetRefreshToken(req.Context(), token)
+	dbTokenRecord, err := a.dbQueries.GetRefreshToken(req.Context(), auth.HashRefreshToken(token))
 	if err != nil {
 		log.Printf("in handlerRefresh, unable to get refresh token: %v", err)
-		w.WriteHeader(401)
+		respondWithDBError(w, 401, err)
 		return
 	}
 
@@ -676,8 +1901,33 @@ func (a *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//Look up the user so the new token carries their current status
+	dbUser, err := a.dbQueries.GetUser(req.Context(), dbTokenRecord.UserID)
+	if err != nil {
+		log.Printf("in handlerRefresh, unable to get user: %v", err)
+		respondWithDBError(w, 401, err)
+		return
+	}
+
+	if dbUser.Suspended {
+		log.Printf("in handlerRefresh, suspended user: %s", dbUser.ID)
+		respondWithError(w, http.StatusForbidden, "Account is suspended")
+		return
+	}
+	if dbUser.DeactivatedAt.Valid {
+		log.Printf("in handlerRefresh, deactivated user: %s", dbUser.ID)
+		respondWithError(w, http.StatusForbidden, "Account is deactivated")
+		return
+	}
+
 	//Create new access token
-	accessToken, err := auth.MakeJWT(dbTokenRecord.UserID, a.secret, 1*time.Hour)
+	audience, err := clientAudience(req)
+	if err != nil {
+		log.Printf("in handlerRefresh, bad client hint: %v", err)
+		respondWithError(w, http.StatusBadRequest, "Unknown client type")
+		return
+	}
+	accessToken, err := auth.MakeJWT(dbUser.ID, dbUser.IsChirpyRed, a.jwtKeys, a.jwtIssuer, 1*time.Hour, audience)
 	if err != nil {
 		log.Printf("in handlerRefresh, unable to make jwt access token: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -704,21 +1954,25 @@ func (a *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 }
 
 func (a *apiConfig) handlerRevoke(w http.ResponseWriter, req *http.Request) {
-	//Check for refresh token in headers
-	token, err := auth.GetBearerToken(req.Header)
+	//Check for refresh token in cookie or headers
+	token, err := getRefreshToken(req)
 	if err != nil {
-		log.Printf("in handlerRevoke, unable to get bearer token: %v", err)
+		log.Printf("in handlerRevoke, unable to get refresh token: %v", err)
 		w.WriteHeader(401)
 		return
 	}
 
-	err = a.dbQueries.RevokeRefreshToken(req.Context(), token)
+	err = a.dbQueries.RevokeRefreshToken(req.Context(), auth.HashRefreshToken(token))
 	if err != nil {
 		log.Printf("in handlerRevoke, unable to revoke: %v", err)
-		w.WriteHeader(401)
+		respondWithDBError(w, 401, err)
 		return
 	}
 
+	if a.refreshCookie {
+		clearRefreshTokenCookie(w)
+		clearCSRFTokenCookie(w)
+	}
 	w.WriteHeader(204)
 }
 
@@ -731,8 +1985,8 @@ func (a *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	//compare
-	if apiKey != a.polkaKey {
+	//compare, with no key configured nothing matches
+	if a.polkaKey == "" || apiKey != a.polkaKey {
 		log.Printf("in handlerPolkaWebhook, api keys do not match")
 		w.WriteHeader(401)
 		return
@@ -747,6 +2001,7 @@ func (a *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, req *http.Request
 	}
 
 	var body reqBody
+	//Polka's payloads aren't ours to police, never decoded strictly
 	decoder := json.NewDecoder(req.Body)
 	err = decoder.Decode(&body)
 	if err != nil {
@@ -773,7 +2028,7 @@ func (a *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, req *http.Request
 	_, err = a.dbQueries.UpgradeUserChirpyRed(req.Context(), userID)
 	if err != nil {
 		log.Printf("in handlerPolkaWebhook, unable to upgrade user: %v", err)
-		w.WriteHeader(404)
+		respondWithDBError(w, 404, err)
 		return
 	}
 
@@ -781,18 +2036,52 @@ func (a *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, req *http.Request
 	w.WriteHeader(204)
 }
 
+// User is the owner's view of their account.
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChripyRed bool      `json:"is_chirpy_red"`
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Email       string     `json:"email"`
+	IsChripyRed bool       `json:"is_chirpy_red"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+func userFromDB(dbUser database.User) User {
+	return User{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		IsChripyRed: dbUser.IsChirpyRed,
+		LastLoginAt: nullTime(dbUser.LastLoginAt),
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// UserProfile is the public view of a user, so it leaves out the email.
+type UserProfile struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Body        string       `json:"body"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Attachments []Attachment `json:"attachments"`
+	QuotedChirp *QuotedChirp `json:"quoted_chirp,omitempty"`
+	Visibility  string       `json:"visibility"`
+	Lang        string       `json:"lang"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maintenanceRetryAfter is what clients are told to wait before retrying a
+// write refused during maintenance.
+const maintenanceRetryAfter = 2 * time.Minute
+
+// middlewareMaintenance refuses writes with a 503 while maintenance mode is
+// on. Reads keep working, and so does /admin so the mode can be turned off.
+func (a *apiConfig) middlewareMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if a.maintenance.Load() && isWrite(req.Method) && !strings.HasPrefix(req.URL.Path, "/admin/") {
+				w.Header().Set("Retry-After", strconv.Itoa(int(maintenanceRetryAfter.Seconds())))
+				respondWithError(w, http.StatusServiceUnavailable, "Down for maintenance, try again later")
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (a *apiConfig) handlerStartMaintenance(w http.ResponseWriter, req *http.Request) {
+	a.setMaintenance(w, req, true)
+}
+
+func (a *apiConfig) handlerStopMaintenance(w http.ResponseWriter, req *http.Request) {
+	a.setMaintenance(w, req, false)
+}
+
+func (a *apiConfig) setMaintenance(w http.ResponseWriter, req *http.Request, enabled bool) {
+	a.maintenance.Store(enabled)
+	log.Printf("maintenance mode set to %t by %s", enabled, req.RemoteAddr)
+
+	type maintenanceResponse struct {
+		Maintenance bool `json:"maintenance"`
+	}
+	respondWithJSON(w, http.StatusOK, maintenanceResponse{Maintenance: enabled})
+}
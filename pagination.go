@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pageSizes bound the "limit" query parameter of paginated endpoints.
+type pageSizes struct {
+	def int32
+	max int32
+}
+
+// pageSizesFromEnv reads DEFAULT_PAGE_SIZE and MAX_PAGE_SIZE, 50 and 100
+// unless set.
+func pageSizesFromEnv() (pageSizes, error) {
+	defSize, err := envUint("DEFAULT_PAGE_SIZE", 50, 31)
+	if err != nil {
+		return pageSizes{}, err
+	}
+	maxSize, err := envUint("MAX_PAGE_SIZE", 100, 31)
+	if err != nil {
+		return pageSizes{}, err
+	}
+	if defSize == 0 || defSize > maxSize {
+		return pageSizes{}, fmt.Errorf("DEFAULT_PAGE_SIZE must be between 1 and MAX_PAGE_SIZE")
+	}
+	return pageSizes{def: int32(defSize), max: int32(maxSize)}, nil
+}
+
+// limit reads the "limit" query parameter. A limit over the max is clamped
+// to it rather than refused, so callers should tell the client the page
+// size they got with setPageSize.
+func (p pageSizes) limit(query url.Values) int32 {
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		return p.def
+	}
+	return int32(min(limit, int(p.max)))
+}
+
+// setPageSize reports the page size that was applied in X-Page-Size.
+func setPageSize(w http.ResponseWriter, size int32) {
+	w.Header().Set("X-Page-Size", strconv.Itoa(int(size)))
+}
+
+// pageOffset reads the "offset" query parameter, defaulting to the start.
+func pageOffset(query url.Values) int32 {
+	offset, err := strconv.ParseInt(query.Get("offset"), 10, 32)
+	if err != nil || offset < 0 {
+		return 0
+	}
+	return int32(offset)
+}
+
+// A cursor marks the last chirp of a page, so the next page starts
+// strictly after it in (created_at, id) order.
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c cursor) encode() string {
+	raw := fmt.Sprintf("%d:%s", c.CreatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, fmt.Errorf("malformed cursor: %w", err)
+	}
+
+	nanosStr, idStr, found := strings.Cut(string(raw), ":")
+	if !found {
+		return cursor{}, fmt.Errorf("malformed cursor")
+	}
+
+	nanos, err := strconv.ParseInt(nanosStr, 10, 64)
+	if err != nil {
+		return cursor{}, fmt.Errorf("malformed cursor timestamp: %w", err)
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return cursor{}, fmt.Errorf("malformed cursor id: %w", err)
+	}
+
+	return cursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
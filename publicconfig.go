@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+)
+
+// PublicConfig is the server's limits and features, for clients to
+// validate against instead of hardcoding them. Nothing secret belongs here.
+type PublicConfig struct {
+	MinChirpLength      int          `json:"min_chirp_length"`
+	MaxChirpLength      int          `json:"max_chirp_length"`
+	MaxChirpLengthRed   int          `json:"max_chirp_length_red"`
+	MaxChirps           int32        `json:"max_chirps"`
+	DefaultPageSize     int32        `json:"default_page_size"`
+	MaxPageSize         int32        `json:"max_page_size"`
+	EditWindowSeconds   int64        `json:"edit_window_seconds,omitempty"`
+	DeleteWindowSeconds int64        `json:"delete_window_seconds,omitempty"`
+	AllowSignups        bool         `json:"allow_signups"`
+	InviteOnly          bool         `json:"invite_only,omitempty"`
+	Features            featureFlags `json:"features"`
+}
+
+func (a *apiConfig) handlerGetConfig(w http.ResponseWriter, req *http.Request) {
+	respondWithJSON(w, http.StatusOK, PublicConfig{
+		MinChirpLength:      a.minChirpLength,
+		MaxChirpLength:      a.maxChirpLength,
+		MaxChirpLengthRed:   a.redChirpLength,
+		MaxChirps:           a.maxChirps,
+		DefaultPageSize:     a.pageSizes.def,
+		MaxPageSize:         a.pageSizes.max,
+		EditWindowSeconds:   int64(a.editWindow.Seconds()),
+		DeleteWindowSeconds: int64(a.deleteWindow.Seconds()),
+		AllowSignups:        a.allowSignups,
+		InviteOnly:          a.inviteOnly,
+		Features:            a.features,
+	})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetConfig(t *testing.T) {
+	a, _, _, _ := newTestAPI(t)
+	a.polkaKey = "polka-secret"
+	a.features.Likes = true
+
+	w := httptest.NewRecorder()
+	a.handlerGetConfig(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+
+	var config PublicConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+		t.Fatalf("unable to decode %s: %v", w.Body, err)
+	}
+	if config.MaxChirpLength != defaultMaxChirpLength || !config.Features.Likes {
+		t.Errorf("expected the configured limits and features, got %+v", config)
+	}
+	for _, secret := range []string{"polka-secret", "secret", "admin"} {
+		if strings.Contains(w.Body.String(), `"`+secret+`"`) {
+			t.Errorf("expected no secrets, found %q in %s", secret, w.Body)
+		}
+	}
+}
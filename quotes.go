@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// QuotedChirp is the live view of a quoted chirp. Once the original is
+// deleted, or if the viewer can't see it, only its id is left and Available
+// is false.
+type QuotedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	Available bool       `json:"available"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Body      string     `json:"body,omitempty"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// loadQuotes fills in the chirps that chirps quote with one query, leaving
+// out any viewer isn't allowed to see.
+func loadQuotes(ctx context.Context, q database.Querier, viewer uuid.NullUUID, chirps []Chirp) error {
+	var ids []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.QuotedChirp != nil {
+			ids = append(ids, chirp.QuotedChirp.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	dbQuoted, err := q.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, dbChirp := range dbQuoted {
+		visible, err := canView(ctx, q, viewer, dbChirp)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			continue
+		}
+		for _, chirp := range chirps {
+			if chirp.QuotedChirp == nil || chirp.QuotedChirp.ID != dbChirp.ID {
+				continue
+			}
+			chirp.QuotedChirp.Available = true
+			chirp.QuotedChirp.CreatedAt = &dbChirp.CreatedAt
+			chirp.QuotedChirp.Body = dbChirp.Body
+			chirp.QuotedChirp.UserID = &dbChirp.UserID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A rateLimiter allows each key a number of requests per window.
+type rateLimiter interface {
+	// allow counts a request for key and reports whether it is within the
+	// limit and, when it isn't, how long until the window resets.
+	allow(ctx context.Context, key string) (bool, time.Duration)
+}
+
+// rateLimitBackend makes the limiters for one store, so the login and chirp
+// limits are kept in the same place. name keeps their counts apart.
+type rateLimitBackend func(name string, limit int, window time.Duration) rateLimiter
+
+// rateLimitBackendFromEnv picks where counts are kept from
+// RATE_LIMIT_BACKEND: in memory by default, which is per instance, or
+// "redis" at REDIS_URL, shared by every instance behind a load balancer.
+func rateLimitBackendFromEnv() (rateLimitBackend, error) {
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		return newMemoryLimiter, nil
+	case "redis":
+		redisURL := os.Getenv("REDIS_URL")
+		if redisURL == "" {
+			return nil, fmt.Errorf("REDIS_URL is required with RATE_LIMIT_BACKEND=redis")
+		}
+		return redisBackend(redisURL)
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BACKEND %q, want memory or redis", backend)
+	}
+}
+
+// limiter makes a limiter, one that allows everything for a limit of 0.
+func (b rateLimitBackend) limiter(name string, limit int, window time.Duration) rateLimiter {
+	if limit == 0 {
+		return unlimited{}
+	}
+	return b(name, limit, window)
+}
+
+type unlimited struct{}
+
+func (unlimited) allow(ctx context.Context, key string) (bool, time.Duration) { return true, 0 }
+
+// memoryLimiter allows each key limit requests per fixed window. All counts
+// reset together when the window rolls over, so memory stays bounded by the
+// clients seen in one window.
+type memoryLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+// newMemoryLimiter is a rateLimitBackend; every limiter has its own counts
+// so the name isn't needed.
+func newMemoryLimiter(name string, limit int, window time.Duration) rateLimiter {
+	return &memoryLimiter{
+		limit:  limit,
+		window: window,
+		counts: map[string]int{},
+	}
+}
+
+func (l *memoryLimiter) allow(ctx context.Context, key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		clear(l.counts)
+	}
+	l.counts[key]++
+	if l.counts[key] <= l.limit {
+		return true, 0
+	}
+	return false, l.windowStart.Add(l.window).Sub(now)
+}
+
+// rateLimitByIP refuses requests from a client past the limiter's limit
+// with a 429, telling it when to come back.
+func (a *apiConfig) rateLimitByIP(limiter rateLimiter, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			ip := a.trustedProxies.clientIP(req)
+			if ok, retryAfter := limiter.allow(req.Context(), ip); !ok {
+				log.Printf("rate limited %s %s from %s", req.Method, req.URL.Path, ip)
+				respondWithRateLimited(w, retryAfter)
+				return
+			}
+			next(w, req)
+		})
+}
+
+// respondWithRateLimited is the 429 for a request refused by a limiter,
+// with Retry-After rounded up to whole seconds so a client waiting that
+// long is never early.
+func respondWithRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondWithError(w, http.StatusTooManyRequests, "Too many requests")
+}
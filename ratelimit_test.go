@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	a := &apiConfig{}
+	limiter := newMemoryLimiter("login", 1, time.Minute)
+	handler := a.rateLimitByIP(limiter, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	send := func() *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/login", nil))
+		return w
+	}
+
+	if w := send(); w.Code != http.StatusNoContent {
+		t.Fatalf("expected the first request through, got %d", w.Code)
+	}
+
+	//part of the window has already gone by
+	limiter.(*memoryLimiter).windowStart = time.Now().Add(-20 * time.Second)
+	w := send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	seconds, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || seconds < 39 || seconds > 40 {
+		t.Errorf("expected Retry-After to be the 40s left in the window, got %q", w.Header().Get("Retry-After"))
+	}
+}
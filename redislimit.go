@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisLimiter is a fixed window limiter with its counts in Redis, so they
+// hold across instances. Each window gets its own key, which expires along
+// with it.
+type redisLimiter struct {
+	client *redis.Client
+	name   string
+	limit  int
+	window time.Duration
+}
+
+func redisBackend(redisURL string) (rateLimitBackend, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	return func(name string, limit int, window time.Duration) rateLimiter {
+		return &redisLimiter{
+			client: client,
+			name:   name,
+			limit:  limit,
+			window: window,
+		}
+	}, nil
+}
+
+// allow lets the request through if Redis can't be reached, so an outage
+// there doesn't take logins and posting down with it.
+func (l *redisLimiter) allow(ctx context.Context, key string) (bool, time.Duration) {
+	now := time.Now()
+	windowIndex := now.UnixNano() / int64(l.window)
+	redisKey := fmt.Sprintf("chirpy:ratelimit:%s:%d:%s", l.name, windowIndex, key)
+
+	pipe := l.client.TxPipeline()
+	count := pipe.Incr(ctx, redisKey)
+	pipe.Expire(ctx, redisKey, l.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("in redisLimiter.allow, unable to count request: %v", err)
+		return true, 0
+	}
+	if count.Val() <= int64(l.limit) {
+		return true, 0
+	}
+	//windows are aligned to multiples of the window length
+	reset := time.Unix(0, (windowIndex+1)*int64(l.window))
+	return false, reset.Sub(now)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "net/http"
+
+// handlerRoot answers "/" itself rather than leaving it to the mux: a
+// redirect to ROOT_REDIRECT if set, otherwise pointers to what is served.
+func (a *apiConfig) handlerRoot(w http.ResponseWriter, req *http.Request) {
+	if a.rootRedirect != "" {
+		http.Redirect(w, req, a.rootRedirect, http.StatusFound)
+		return
+	}
+
+	type response struct {
+		Name string `json:"name"`
+		App  string `json:"app"`
+		API  string `json:"api"`
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		Name: "chirpy",
+		App:  "/app/",
+		API:  "/api/",
+	})
+}
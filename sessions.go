@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+type Session struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sessionID identifies a refresh token without revealing it.
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+func sessionFromDB(record database.RefreshToken) Session {
+	return Session{
+		ID:        sessionID(record.Token),
+		CreatedAt: record.CreatedAt,
+		ExpiresAt: record.ExpiresAt,
+	}
+}
+
+func (a *apiConfig) handlerGetSessions(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerGetSessions, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	records, err := a.dbQueries.GetActiveRefreshTokensForUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetSessions, unable to get refresh tokens: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sessions := []Session{}
+	for _, record := range records {
+		sessions = append(sessions, sessionFromDB(record))
+	}
+
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (a *apiConfig) handlerDeleteSession(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerDeleteSession, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	//Only the user's own active sessions can be matched
+	records, err := a.dbQueries.GetActiveRefreshTokensForUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerDeleteSession, unable to get refresh tokens: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	id := req.PathValue("id")
+	for _, record := range records {
+		if sessionID(record.Token) != id {
+			continue
+		}
+
+		err = a.dbQueries.RevokeRefreshToken(req.Context(), record.Token)
+		if err != nil {
+			log.Printf("in handlerDeleteSession, unable to revoke refresh token: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
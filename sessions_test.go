@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func TestRevokeOldestSessions(t *testing.T) {
+	ctx := context.Background()
+	_, store, user, _ := newTestAPI(t)
+
+	for i := range 3 {
+		args := database.CreateRefreshTokenParams{Token: fmt.Sprintf("token-%d", i), UserID: user.ID}
+		if _, err := store.CreateRefreshToken(ctx, args); err != nil {
+			t.Fatalf("CreateRefreshToken failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := revokeOldestSessions(ctx, store, user.ID, 1); err != nil {
+		t.Fatalf("revokeOldestSessions failed: %v", err)
+	}
+	records, err := store.GetActiveRefreshTokensForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetActiveRefreshTokensForUser failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Token != "token-2" {
+		t.Errorf("expected only the newest session to be left, got %v", records)
+	}
+}
@@ -0,0 +1,27 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.user_id = $1
+  AND users.deactivated_at IS NULL
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+  AND (chirps.visibility = 'public'
+    OR chirps.user_id = $1
+    OR (chirps.visibility = 'followers' AND EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = $1 AND follows.followee_id = chirps.user_id)))
+ORDER BY bookmarks.created_at DESC;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- name: CreateChirpAttachment :exec
+INSERT INTO chirp_attachments (chirp_id, position, url, type)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetChirpAttachments :many
+SELECT *
+FROM chirp_attachments
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
\ No newline at end of file
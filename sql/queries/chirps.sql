@@ -1,21 +1,29 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, visibility, lang, expires_at)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
--- name: DeleteAllChirps :exec
+-- name: DeleteAllChirps :execrows
 DELETE FROM chirps;
 
+-- name: DeleteExpiredChirps :execrows
+DELETE FROM chirps
+WHERE expires_at IS NOT NULL AND expires_at <= NOW();
+
 -- name: GetAllChirps :many
 SELECT *
 FROM chirps
-ORDER BY created_at ASC;
+ORDER BY created_at ASC, id ASC;
 
 -- name: GetChirp :one
 SELECT *
@@ -23,6 +31,11 @@ FROM chirps
 WHERE id = $1
 LIMIT 1;
 
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 where id = $1;
@@ -31,4 +44,66 @@ where id = $1;
 SELECT *
 FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+ORDER BY created_at ASC, id ASC;
+
+-- name: GetTimeline :many
+SELECT chirps.*
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN follows ON follows.followee_id = chirps.user_id AND follows.follower_id = sqlc.arg(user_id)
+WHERE (chirps.user_id = sqlc.arg(user_id) OR follows.follower_id IS NOT NULL)
+  AND users.deactivated_at IS NULL
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+  AND (chirps.user_id = sqlc.arg(user_id) OR chirps.visibility <> 'private')
+  AND (sqlc.narg(before_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+  AND users.deactivated_at IS NULL
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+  AND (NOT sqlc.arg(author_red)::boolean OR users.is_chirpy_red)
+  AND (chirps.visibility = 'public'
+    OR chirps.user_id = sqlc.narg(viewer_id)::uuid
+    OR (chirps.visibility = 'followers' AND EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = sqlc.narg(viewer_id)::uuid AND follows.followee_id = chirps.user_id)))
+  AND (sqlc.narg(since_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg(since_created_at)::timestamp, sqlc.narg(since_id)::uuid))
+  AND (sqlc.narg(lang)::text IS NULL OR chirps.lang = sqlc.narg(lang)::text)
+ORDER BY
+    CASE WHEN sqlc.arg(newest_first)::boolean THEN chirps.created_at END DESC,
+    CASE WHEN sqlc.arg(newest_first)::boolean THEN chirps.id END DESC,
+    chirps.created_at ASC,
+    chirps.id ASC
+LIMIT sqlc.arg(max_results);
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET updated_at = NOW(), body = $2, lang = $3
+WHERE id = $1
+  AND (sqlc.narg(if_updated_at)::timestamp IS NULL OR updated_at = sqlc.narg(if_updated_at)::timestamp)
+RETURNING *;
+
+-- name: GetUserChirpStats :one
+SELECT
+    COUNT(*) AS chirp_count,
+    MIN(created_at)::timestamp AS first_chirp_at,
+    MAX(created_at)::timestamp AS last_chirp_at
+FROM chirps
+WHERE user_id = $1
+  AND visibility = 'public'
+  AND (expires_at IS NULL OR expires_at > NOW())
+GROUP BY user_id;
+
+-- name: HasRecentDuplicateChirp :one
+SELECT EXISTS (
+    SELECT 1
+    FROM chirps
+    WHERE user_id = $1 AND body = $2 AND created_at > sqlc.arg(not_before)
+);
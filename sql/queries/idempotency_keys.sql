@@ -0,0 +1,19 @@
+-- name: CreateIdempotencyKey :exec
+INSERT INTO idempotency_keys (user_id, key, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, key) DO UPDATE
+SET chirp_id = EXCLUDED.chirp_id, created_at = EXCLUDED.created_at;
+
+-- name: GetIdempotentChirp :one
+SELECT chirps.*
+FROM chirps
+JOIN idempotency_keys ON idempotency_keys.chirp_id = chirps.id
+WHERE idempotency_keys.user_id = $1
+  AND idempotency_keys.key = $2
+  AND idempotency_keys.created_at > sqlc.arg(not_before)
+LIMIT 1;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- name: CreateInviteCode :one
+INSERT INTO invite_codes (code, created_at, expires_at, max_uses, uses)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    0
+)
+RETURNING *;
+
+-- name: UseInviteCode :one
+UPDATE invite_codes
+SET uses = uses + 1
+WHERE code = $1
+  AND uses < max_uses
+  AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING *;
+
+-- name: CreateInviteCodeUse :exec
+INSERT INTO invite_code_uses (code, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+);
@@ -0,0 +1,47 @@
+-- name: CreateLike :exec
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteLike :exec
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: CountLikes :one
+SELECT COUNT(*)
+FROM likes
+WHERE chirp_id = $1;
+
+-- name: CountLikesReceived :one
+SELECT COUNT(*)
+FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE chirps.user_id = $1
+  AND chirps.visibility = 'public'
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW());
+
+-- name: GetLikesByUser :many
+SELECT *
+FROM likes
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetLikedChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN likes ON likes.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id
+WHERE likes.user_id = sqlc.arg(user_id)
+  AND users.deactivated_at IS NULL
+  AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+  AND (chirps.visibility = 'public'
+    OR chirps.user_id = sqlc.narg(viewer_id)::uuid
+    OR (chirps.visibility = 'followers' AND EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = sqlc.narg(viewer_id)::uuid AND follows.followee_id = chirps.user_id)))
+ORDER BY likes.created_at DESC, chirps.id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
\ No newline at end of file
@@ -9,9 +9,16 @@ VALUES (
 )
 RETURNING *;
 
--- name: DeleteAllUsers :exec
+-- name: DeleteAllUsers :execrows
 DELETE FROM users;
 
+-- name: EmailExists :one
+SELECT EXISTS (
+    SELECT 1
+    FROM users
+    WHERE email = $1
+);
+
 -- name: GetUserByEmail :one
 SELECT *
 FROM users
@@ -28,4 +35,70 @@ RETURNING *;
 UPDATE users
 SET updated_at = NOW(), is_chirpy_red = true
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: DowngradeUserChirpyRed :one
+UPDATE users
+SET updated_at = NOW(), is_chirpy_red = false
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUser :one
+SELECT *
+FROM users
+WHERE id = $1
+LIMIT 1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET updated_at = NOW(), hashed_password = $2
+WHERE id = $1;
+
+-- name: UpdateUserLastLogin :exec
+UPDATE users
+SET last_login_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserSuspended :one
+UPDATE users
+SET updated_at = NOW(), suspended = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUsersByIDs :many
+SELECT
+    id,
+    created_at,
+    is_chirpy_red,
+    (SELECT COUNT(*) FROM follows WHERE followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = users.id) AS following_count
+FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+  AND deactivated_at IS NULL
+ORDER BY created_at;
+
+-- name: ListUsers :many
+SELECT *
+FROM users
+ORDER BY created_at, id
+LIMIT $1 OFFSET $2;
+
+-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users;
+
+-- name: DeactivateUser :one
+UPDATE users
+SET updated_at = NOW(), deactivated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ReactivateUser :one
+UPDATE users
+SET updated_at = NOW(), deactivated_at = NULL
+WHERE id = $1 AND deactivated_at > $2
+RETURNING *;
+
+-- name: PurgeDeactivatedUsers :execrows
+DELETE FROM users
+WHERE deactivated_at <= $1;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN last_login_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN last_login_at;
\ No newline at end of file
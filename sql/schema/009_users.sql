@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ADD CONSTRAINT users_email_length CHECK (octet_length(email) <= 254);
+
+-- +goose Down
+ALTER TABLE users
+DROP CONSTRAINT users_email_length;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE chirp_revisions (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- existing chirps start their history at creation
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+SELECT gen_random_uuid(), id, body, created_at
+FROM chirps;
+
+-- +goose Down
+DROP TABLE chirp_revisions;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +goose Up
+-- tokens are stored as sha256 hex digests; rehash so existing sessions keep working
+UPDATE refresh_tokens
+SET token = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+
+-- +goose Down
+-- digests can't be reversed, so existing sessions are dropped
+DELETE FROM refresh_tokens;
\ No newline at end of file
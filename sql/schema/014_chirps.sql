@@ -0,0 +1,8 @@
+-- +goose Up
+-- no foreign key: a quote outlives the chirp it quotes
+ALTER TABLE chirps
+ADD COLUMN quoted_chirp_id UUID;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN quoted_chirp_id;
\ No newline at end of file
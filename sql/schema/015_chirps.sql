@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TYPE chirp_visibility AS ENUM ('public', 'followers', 'private');
+
+ALTER TABLE chirps
+ADD COLUMN visibility chirp_visibility NOT NULL DEFAULT 'public';
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN visibility;
+
+DROP TYPE chirp_visibility;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +goose Up
+-- empty when the language couldn't be detected
+ALTER TABLE chirps
+ADD COLUMN lang TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN lang;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +goose Up
+-- set while a deleted account can still be reactivated, purged after that
+ALTER TABLE users
+ADD COLUMN deactivated_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN deactivated_at;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+-- only a SHA-256 of each key is kept, the key itself is shown once
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE api_keys;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- a code signs up at most max_uses users, until expires_at if it has one
+CREATE TABLE invite_codes (
+    code TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    max_uses INTEGER NOT NULL,
+    uses INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE invite_code_uses (
+    code TEXT NOT NULL REFERENCES invite_codes(code) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (code, user_id)
+);
+
+-- +goose Down
+DROP TABLE invite_code_uses;
+DROP TABLE invite_codes;
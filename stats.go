@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserStats struct {
+	UserID        uuid.UUID  `json:"user_id"`
+	ChirpCount    int64      `json:"chirp_count"`
+	FirstChirpAt  *time.Time `json:"first_chirp_at"`
+	LastChirpAt   *time.Time `json:"last_chirp_at"`
+	LikesReceived int64      `json:"likes_received"`
+}
+
+func (a *apiConfig) handlerGetUserStats(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		log.Printf("in handlerGetUserStats, could not parse user id: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dbUser, err := a.readQueries.GetUser(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetUserStats, unable to get user: %v", err)
+		respondWithDBError(w, http.StatusNotFound, err)
+		return
+	}
+	if dbUser.DeactivatedAt.Valid {
+		log.Printf("in handlerGetUserStats, deactivated user: %s", userID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	stats := UserStats{UserID: userID}
+	row, err := a.readQueries.GetUserChirpStats(req.Context(), userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		//no chirps yet, leave the zero counts
+	case err != nil:
+		log.Printf("in handlerGetUserStats, unable to get chirp stats: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	default:
+		stats.ChirpCount = row.ChirpCount
+		stats.FirstChirpAt = &row.FirstChirpAt
+		stats.LastChirpAt = &row.LastChirpAt
+	}
+
+	stats.LikesReceived, err = a.readQueries.CountLikesReceived(req.Context(), userID)
+	if err != nil {
+		log.Printf("in handlerGetUserStats, unable to count likes: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+func TestUserStatsOnlyCountPublicChirps(t *testing.T) {
+	ctx := context.Background()
+	a, store, user, _ := newTestAPI(t)
+	fan, err := store.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	past := sql.NullTime{Time: time.Now().UTC().Add(-time.Minute), Valid: true}
+	for _, args := range []database.CreateChirpParams{
+		{Body: "public", UserID: user.ID},
+		{Body: "also public", UserID: user.ID},
+		{Body: "private", UserID: user.ID, Visibility: database.ChirpVisibilityPrivate},
+		{Body: "followers", UserID: user.ID, Visibility: database.ChirpVisibilityFollowers},
+		{Body: "expired", UserID: user.ID, ExpiresAt: past},
+	} {
+		chirp, err := store.CreateChirp(ctx, args)
+		if err != nil {
+			t.Fatalf("CreateChirp failed: %v", err)
+		}
+		//the fan likes everything, only likes of public chirps count
+		for _, liker := range []database.User{user, fan} {
+			if err := store.CreateLike(ctx, database.CreateLikeParams{UserID: liker.ID, ChirpID: chirp.ID}); err != nil {
+				t.Fatalf("CreateLike failed: %v", err)
+			}
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+user.ID.String()+"/stats", nil)
+	req.SetPathValue("id", user.ID.String())
+	w := httptest.NewRecorder()
+	a.handlerGetUserStats(w, req)
+
+	var stats UserStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected stats, got %d %s", w.Code, w.Body)
+	}
+	if stats.ChirpCount != 2 || stats.LikesReceived != 4 {
+		t.Errorf("expected 2 chirps and 4 likes received, got %+v", stats)
+	}
+}
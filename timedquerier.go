@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// timedQuerier gives every query its own deadline, so a stuck query can't
+// tie up a request indefinitely, and its own tracing span. Queries taking
+// slowQuery or longer are logged, unless it is 0.
+type timedQuerier struct {
+	next      database.Querier
+	timeout   time.Duration
+	slowQuery time.Duration
+}
+
+var _ database.Querier = (*timedQuerier)(nil)
+
+func newTimedQuerier(next database.Querier, timeout, slowQuery time.Duration) *timedQuerier {
+	return &timedQuerier{next: next, timeout: timeout, slowQuery: slowQuery}
+}
+
+// start derives the context for one query. The returned func must be
+// called once the query is done.
+func (q *timedQuerier) start(ctx context.Context, query string) (context.Context, func()) {
+	ctx, span := tracer.Start(ctx, "db "+query, trace.WithSpanKind(trace.SpanKindClient))
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	began := time.Now()
+	return ctx, func() {
+		cancel()
+		span.End()
+		if elapsed := time.Since(began); q.slowQuery > 0 && elapsed >= q.slowQuery {
+			log.Printf("slow query %s took %s", query, elapsed)
+		}
+	}
+}
+
+// timed hands each transaction its queries wrapped in a timedQuerier.
+func (run txRunner) timed(timeout, slowQuery time.Duration) txRunner {
+	return func(ctx context.Context, fn func(q database.Querier) error) error {
+		return run(ctx, func(q database.Querier) error {
+			return fn(newTimedQuerier(q, timeout, slowQuery))
+		})
+	}
+}
+
+// Querier methods below are mechanical wrappers.
+
+func (q *timedQuerier) CountFollowers(ctx context.Context, followeeID uuid.UUID) (int64, error) {
+	ctx, done := q.start(ctx, "CountFollowers")
+	defer done()
+	return q.next.CountFollowers(ctx, followeeID)
+}
+
+func (q *timedQuerier) CountFollowing(ctx context.Context, followerID uuid.UUID) (int64, error) {
+	ctx, done := q.start(ctx, "CountFollowing")
+	defer done()
+	return q.next.CountFollowing(ctx, followerID)
+}
+
+func (q *timedQuerier) CountLikes(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	ctx, done := q.start(ctx, "CountLikes")
+	defer done()
+	return q.next.CountLikes(ctx, chirpID)
+}
+
+func (q *timedQuerier) CountLikesReceived(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, done := q.start(ctx, "CountLikesReceived")
+	defer done()
+	return q.next.CountLikesReceived(ctx, userID)
+}
+
+func (q *timedQuerier) CountUsers(ctx context.Context) (int64, error) {
+	ctx, done := q.start(ctx, "CountUsers")
+	defer done()
+	return q.next.CountUsers(ctx)
+}
+
+func (q *timedQuerier) CreateAPIKey(ctx context.Context, arg database.CreateAPIKeyParams) (database.ApiKey, error) {
+	ctx, done := q.start(ctx, "CreateAPIKey")
+	defer done()
+	return q.next.CreateAPIKey(ctx, arg)
+}
+
+func (q *timedQuerier) CreateBookmark(ctx context.Context, arg database.CreateBookmarkParams) error {
+	ctx, done := q.start(ctx, "CreateBookmark")
+	defer done()
+	return q.next.CreateBookmark(ctx, arg)
+}
+
+func (q *timedQuerier) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	ctx, done := q.start(ctx, "CreateChirp")
+	defer done()
+	return q.next.CreateChirp(ctx, arg)
+}
+
+func (q *timedQuerier) CreateChirpAttachment(ctx context.Context, arg database.CreateChirpAttachmentParams) error {
+	ctx, done := q.start(ctx, "CreateChirpAttachment")
+	defer done()
+	return q.next.CreateChirpAttachment(ctx, arg)
+}
+
+func (q *timedQuerier) CreateChirpRevision(ctx context.Context, arg database.CreateChirpRevisionParams) error {
+	ctx, done := q.start(ctx, "CreateChirpRevision")
+	defer done()
+	return q.next.CreateChirpRevision(ctx, arg)
+}
+
+func (q *timedQuerier) CreateFollow(ctx context.Context, arg database.CreateFollowParams) error {
+	ctx, done := q.start(ctx, "CreateFollow")
+	defer done()
+	return q.next.CreateFollow(ctx, arg)
+}
+
+func (q *timedQuerier) CreateIdempotencyKey(ctx context.Context, arg database.CreateIdempotencyKeyParams) error {
+	ctx, done := q.start(ctx, "CreateIdempotencyKey")
+	defer done()
+	return q.next.CreateIdempotencyKey(ctx, arg)
+}
+
+func (q *timedQuerier) CreateInviteCode(ctx context.Context, arg database.CreateInviteCodeParams) (database.InviteCode, error) {
+	ctx, done := q.start(ctx, "CreateInviteCode")
+	defer done()
+	return q.next.CreateInviteCode(ctx, arg)
+}
+
+func (q *timedQuerier) CreateInviteCodeUse(ctx context.Context, arg database.CreateInviteCodeUseParams) error {
+	ctx, done := q.start(ctx, "CreateInviteCodeUse")
+	defer done()
+	return q.next.CreateInviteCodeUse(ctx, arg)
+}
+
+func (q *timedQuerier) CreateLike(ctx context.Context, arg database.CreateLikeParams) error {
+	ctx, done := q.start(ctx, "CreateLike")
+	defer done()
+	return q.next.CreateLike(ctx, arg)
+}
+
+func (q *timedQuerier) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	ctx, done := q.start(ctx, "CreateRefreshToken")
+	defer done()
+	return q.next.CreateRefreshToken(ctx, arg)
+}
+
+func (q *timedQuerier) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	ctx, done := q.start(ctx, "CreateUser")
+	defer done()
+	return q.next.CreateUser(ctx, arg)
+}
+
+func (q *timedQuerier) DeactivateUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	ctx, done := q.start(ctx, "DeactivateUser")
+	defer done()
+	return q.next.DeactivateUser(ctx, id)
+}
+
+func (q *timedQuerier) DeleteAPIKey(ctx context.Context, arg database.DeleteAPIKeyParams) (int64, error) {
+	ctx, done := q.start(ctx, "DeleteAPIKey")
+	defer done()
+	return q.next.DeleteAPIKey(ctx, arg)
+}
+
+func (q *timedQuerier) DeleteAllChirps(ctx context.Context) (int64, error) {
+	ctx, done := q.start(ctx, "DeleteAllChirps")
+	defer done()
+	return q.next.DeleteAllChirps(ctx)
+}
+
+func (q *timedQuerier) DeleteAllUsers(ctx context.Context) (int64, error) {
+	ctx, done := q.start(ctx, "DeleteAllUsers")
+	defer done()
+	return q.next.DeleteAllUsers(ctx)
+}
+
+func (q *timedQuerier) DeleteBookmark(ctx context.Context, arg database.DeleteBookmarkParams) error {
+	ctx, done := q.start(ctx, "DeleteBookmark")
+	defer done()
+	return q.next.DeleteBookmark(ctx, arg)
+}
+
+func (q *timedQuerier) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	ctx, done := q.start(ctx, "DeleteChirp")
+	defer done()
+	return q.next.DeleteChirp(ctx, id)
+}
+
+func (q *timedQuerier) DeleteExpiredChirps(ctx context.Context) (int64, error) {
+	ctx, done := q.start(ctx, "DeleteExpiredChirps")
+	defer done()
+	return q.next.DeleteExpiredChirps(ctx)
+}
+
+func (q *timedQuerier) DeleteFollow(ctx context.Context, arg database.DeleteFollowParams) error {
+	ctx, done := q.start(ctx, "DeleteFollow")
+	defer done()
+	return q.next.DeleteFollow(ctx, arg)
+}
+
+func (q *timedQuerier) DeleteLike(ctx context.Context, arg database.DeleteLikeParams) error {
+	ctx, done := q.start(ctx, "DeleteLike")
+	defer done()
+	return q.next.DeleteLike(ctx, arg)
+}
+
+func (q *timedQuerier) DowngradeUserChirpyRed(ctx context.Context, id uuid.UUID) (database.User, error) {
+	ctx, done := q.start(ctx, "DowngradeUserChirpyRed")
+	defer done()
+	return q.next.DowngradeUserChirpyRed(ctx, id)
+}
+
+func (q *timedQuerier) EmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, done := q.start(ctx, "EmailExists")
+	defer done()
+	return q.next.EmailExists(ctx, email)
+}
+
+func (q *timedQuerier) GetAPIKeyByHash(ctx context.Context, keyHash string) (database.ApiKey, error) {
+	ctx, done := q.start(ctx, "GetAPIKeyByHash")
+	defer done()
+	return q.next.GetAPIKeyByHash(ctx, keyHash)
+}
+
+func (q *timedQuerier) GetActiveRefreshTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	ctx, done := q.start(ctx, "GetActiveRefreshTokensForUser")
+	defer done()
+	return q.next.GetActiveRefreshTokensForUser(ctx, userID)
+}
+
+func (q *timedQuerier) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetAllChirps")
+	defer done()
+	return q.next.GetAllChirps(ctx)
+}
+
+func (q *timedQuerier) GetBookmarkedChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetBookmarkedChirps")
+	defer done()
+	return q.next.GetBookmarkedChirps(ctx, userID)
+}
+
+func (q *timedQuerier) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetChirp")
+	defer done()
+	return q.next.GetChirp(ctx, id)
+}
+
+func (q *timedQuerier) GetChirpAttachments(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpAttachment, error) {
+	ctx, done := q.start(ctx, "GetChirpAttachments")
+	defer done()
+	return q.next.GetChirpAttachments(ctx, chirpIds)
+}
+
+func (q *timedQuerier) GetChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error) {
+	ctx, done := q.start(ctx, "GetChirpRevisions")
+	defer done()
+	return q.next.GetChirpRevisions(ctx, chirpID)
+}
+
+func (q *timedQuerier) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetChirpsByAuthor")
+	defer done()
+	return q.next.GetChirpsByAuthor(ctx, userID)
+}
+
+func (q *timedQuerier) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetChirpsByIDs")
+	defer done()
+	return q.next.GetChirpsByIDs(ctx, ids)
+}
+
+func (q *timedQuerier) GetFollowing(ctx context.Context, followerID uuid.UUID) ([]database.Follow, error) {
+	ctx, done := q.start(ctx, "GetFollowing")
+	defer done()
+	return q.next.GetFollowing(ctx, followerID)
+}
+
+func (q *timedQuerier) GetIdempotentChirp(ctx context.Context, arg database.GetIdempotentChirpParams) (database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetIdempotentChirp")
+	defer done()
+	return q.next.GetIdempotentChirp(ctx, arg)
+}
+
+func (q *timedQuerier) GetLikedChirps(ctx context.Context, arg database.GetLikedChirpsParams) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetLikedChirps")
+	defer done()
+	return q.next.GetLikedChirps(ctx, arg)
+}
+
+func (q *timedQuerier) GetLikesByUser(ctx context.Context, userID uuid.UUID) ([]database.Like, error) {
+	ctx, done := q.start(ctx, "GetLikesByUser")
+	defer done()
+	return q.next.GetLikesByUser(ctx, userID)
+}
+
+func (q *timedQuerier) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	ctx, done := q.start(ctx, "GetRefreshToken")
+	defer done()
+	return q.next.GetRefreshToken(ctx, token)
+}
+
+func (q *timedQuerier) GetTimeline(ctx context.Context, arg database.GetTimelineParams) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "GetTimeline")
+	defer done()
+	return q.next.GetTimeline(ctx, arg)
+}
+
+func (q *timedQuerier) GetUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	ctx, done := q.start(ctx, "GetUser")
+	defer done()
+	return q.next.GetUser(ctx, id)
+}
+
+func (q *timedQuerier) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	ctx, done := q.start(ctx, "GetUserByEmail")
+	defer done()
+	return q.next.GetUserByEmail(ctx, email)
+}
+
+func (q *timedQuerier) GetUserChirpStats(ctx context.Context, userID uuid.UUID) (database.GetUserChirpStatsRow, error) {
+	ctx, done := q.start(ctx, "GetUserChirpStats")
+	defer done()
+	return q.next.GetUserChirpStats(ctx, userID)
+}
+
+func (q *timedQuerier) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]database.GetUsersByIDsRow, error) {
+	ctx, done := q.start(ctx, "GetUsersByIDs")
+	defer done()
+	return q.next.GetUsersByIDs(ctx, ids)
+}
+
+func (q *timedQuerier) HasRecentDuplicateChirp(ctx context.Context, arg database.HasRecentDuplicateChirpParams) (bool, error) {
+	ctx, done := q.start(ctx, "HasRecentDuplicateChirp")
+	defer done()
+	return q.next.HasRecentDuplicateChirp(ctx, arg)
+}
+
+func (q *timedQuerier) IsFollowing(ctx context.Context, arg database.IsFollowingParams) (bool, error) {
+	ctx, done := q.start(ctx, "IsFollowing")
+	defer done()
+	return q.next.IsFollowing(ctx, arg)
+}
+
+func (q *timedQuerier) ListAPIKeysForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiKey, error) {
+	ctx, done := q.start(ctx, "ListAPIKeysForUser")
+	defer done()
+	return q.next.ListAPIKeysForUser(ctx, userID)
+}
+
+func (q *timedQuerier) ListChirps(ctx context.Context, arg database.ListChirpsParams) ([]database.Chirp, error) {
+	ctx, done := q.start(ctx, "ListChirps")
+	defer done()
+	return q.next.ListChirps(ctx, arg)
+}
+
+func (q *timedQuerier) ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.User, error) {
+	ctx, done := q.start(ctx, "ListUsers")
+	defer done()
+	return q.next.ListUsers(ctx, arg)
+}
+
+func (q *timedQuerier) PurgeDeactivatedUsers(ctx context.Context, deactivatedAt sql.NullTime) (int64, error) {
+	ctx, done := q.start(ctx, "PurgeDeactivatedUsers")
+	defer done()
+	return q.next.PurgeDeactivatedUsers(ctx, deactivatedAt)
+}
+
+func (q *timedQuerier) ReactivateUser(ctx context.Context, arg database.ReactivateUserParams) (database.User, error) {
+	ctx, done := q.start(ctx, "ReactivateUser")
+	defer done()
+	return q.next.ReactivateUser(ctx, arg)
+}
+
+func (q *timedQuerier) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, done := q.start(ctx, "RevokeAllRefreshTokensForUser")
+	defer done()
+	return q.next.RevokeAllRefreshTokensForUser(ctx, userID)
+}
+
+func (q *timedQuerier) RevokeRefreshToken(ctx context.Context, token string) error {
+	ctx, done := q.start(ctx, "RevokeRefreshToken")
+	defer done()
+	return q.next.RevokeRefreshToken(ctx, token)
+}
+
+func (q *timedQuerier) SetUserSuspended(ctx context.Context, arg database.SetUserSuspendedParams) (database.User, error) {
+	ctx, done := q.start(ctx, "SetUserSuspended")
+	defer done()
+	return q.next.SetUserSuspended(ctx, arg)
+}
+
+func (q *timedQuerier) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error) {
+	ctx, done := q.start(ctx, "UpdateChirpBody")
+	defer done()
+	return q.next.UpdateChirpBody(ctx, arg)
+}
+
+func (q *timedQuerier) UpdateUserEmailAndPass(ctx context.Context, arg database.UpdateUserEmailAndPassParams) (database.User, error) {
+	ctx, done := q.start(ctx, "UpdateUserEmailAndPass")
+	defer done()
+	return q.next.UpdateUserEmailAndPass(ctx, arg)
+}
+
+func (q *timedQuerier) UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error {
+	ctx, done := q.start(ctx, "UpdateUserLastLogin")
+	defer done()
+	return q.next.UpdateUserLastLogin(ctx, id)
+}
+
+func (q *timedQuerier) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	ctx, done := q.start(ctx, "UpdateUserPassword")
+	defer done()
+	return q.next.UpdateUserPassword(ctx, arg)
+}
+
+func (q *timedQuerier) UpgradeUserChirpyRed(ctx context.Context, id uuid.UUID) (database.User, error) {
+	ctx, done := q.start(ctx, "UpgradeUserChirpyRed")
+	defer done()
+	return q.next.UpgradeUserChirpyRed(ctx, id)
+}
+
+func (q *timedQuerier) UseInviteCode(ctx context.Context, code string) (database.InviteCode, error) {
+	ctx, done := q.start(ctx, "UseInviteCode")
+	defer done()
+	return q.next.UseInviteCode(ctx, code)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kbm-ky/chirpy/internal/memstore"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	var logs bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	q := newTimedQuerier(memstore.New(), time.Second, 0)
+	if _, err := q.CountUsers(context.Background()); err != nil {
+		t.Fatalf("CountUsers failed: %v", err)
+	}
+	if strings.Contains(logs.String(), "slow query") {
+		t.Errorf("expected no slow query log with the threshold off, got %q", logs.String())
+	}
+
+	q.slowQuery = time.Nanosecond
+	if _, err := q.CountUsers(context.Background()); err != nil {
+		t.Fatalf("CountUsers failed: %v", err)
+	}
+	if !strings.Contains(logs.String(), "slow query CountUsers took") {
+		t.Errorf("expected the query to be logged as slow, got %q", logs.String())
+	}
+}
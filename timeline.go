@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// timelinePage reads the page of userID's timeline the query asks for, with
+// the cursor for the next page if there may be one.
+func (a *apiConfig) timelinePage(ctx context.Context, userID uuid.UUID, query url.Values) ([]database.Chirp, string, error) {
+	timelineArgs := database.GetTimelineParams{
+		UserID:    userID,
+		PageLimit: a.pageSizes.limit(query),
+	}
+
+	//resume after the previous page, if any
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		c, err := decodeCursor(cursorStr)
+		if err != nil {
+			log.Printf("in timelinePage, bad cursor: %v", err)
+			return nil, "", errInvalidCursor
+		}
+		timelineArgs.BeforeCreatedAt = sql.NullTime{Time: c.CreatedAt, Valid: true}
+		timelineArgs.BeforeID = uuid.NullUUID{UUID: c.ID, Valid: true}
+	}
+
+	dbChirps, err := a.readQueries.GetTimeline(ctx, timelineArgs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	//a full page means there may be more
+	next := ""
+	if len(dbChirps) == int(timelineArgs.PageLimit) {
+		last := dbChirps[len(dbChirps)-1]
+		next = cursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+	return dbChirps, next, nil
+}
+
+func (a *apiConfig) handlerGetTimeline(w http.ResponseWriter, req *http.Request) {
+	//Authenticate
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in handlerGetTimeline, unable to authenticate: %v", err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	query := req.URL.Query()
+	setPageSize(w, a.pageSizes.limit(query))
+	dbChirps, next, err := a.timelinePage(req.Context(), userID, query)
+	if errors.Is(err, errInvalidCursor) {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("in handlerGetTimeline, unable to get timeline: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	chirps, err := chirpsFromDB(req.Context(), a.readQueries, uuid.NullUUID{UUID: userID, Valid: true}, dbChirps)
+	if err != nil {
+		log.Printf("in handlerGetTimeline, unable to load chirps: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	respondWithJSON(w, http.StatusOK, chirps)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// txRunner runs fn against queries bound to a single transaction, which is
+// committed if fn returns nil and rolled back otherwise.
+type txRunner func(ctx context.Context, fn func(q database.Querier) error) error
+
+func postgresTx(db *sql.DB) txRunner {
+	return func(ctx context.Context, fn func(q database.Querier) error) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(database.New(db).WithTx(tx)); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("in postgresTx, unable to roll back: %v", rbErr)
+			}
+			return err
+		}
+
+		return tx.Commit()
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/mail"
+)
+
+// fieldErrors collects every validation failure in a request, so a client
+// can fix them all in one round trip instead of one at a time.
+type fieldErrors []fieldError
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *fieldErrors) add(field, message string) {
+	*e = append(*e, fieldError{Field: field, Message: message})
+}
+
+// respondWithFieldErrors answers a request that failed validation with a
+// 400 listing each problem.
+func respondWithFieldErrors(w http.ResponseWriter, errs fieldErrors) {
+	type errorsResponse struct {
+		Errors fieldErrors `json:"errors"`
+	}
+	respondWithJSON(w, http.StatusBadRequest, errorsResponse{Errors: errs})
+}
+
+// validateCredentials checks the email and password a user signs up or
+// updates their account with.
+func validateCredentials(email, password string) fieldErrors {
+	var errs fieldErrors
+	switch {
+	case email == "":
+		errs.add("email", "Email is required")
+	case len(email) > maxEmailLength:
+		errs.add("email", "Email is too long")
+	case !isEmailAddress(email):
+		errs.add("email", "Email is not a valid address")
+	}
+	if password == "" {
+		errs.add("password", "Password is required")
+	}
+	return errs
+}
+
+// isEmailAddress reports whether email is a bare address, without a
+// display name or angle brackets around it.
+func isEmailAddress(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
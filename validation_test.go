@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignupReportsEveryInvalidField(t *testing.T) {
+	a, _, _, _ := newTestAPI(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"email":"not an email","password":""}`))
+	w := httptest.NewRecorder()
+	a.handlerUsers(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var resp struct {
+		Errors []fieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode response %s: %v", w.Body, err)
+	}
+	var fields []string
+	for _, e := range resp.Errors {
+		fields = append(fields, e.Field)
+	}
+	if strings.Join(fields, ",") != "email,password" {
+		t.Errorf("got errors for %v, want email and password", fields)
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	cases := []struct {
+		email    string
+		password string
+		want     int
+	}{
+		{"a@example.com", "hunter2", 0},
+		{"", "hunter2", 1},
+		{"Alice <a@example.com>", "hunter2", 1},
+		{strings.Repeat("a", maxEmailLength) + "@example.com", "", 2},
+	}
+
+	for _, c := range cases {
+		if errs := validateCredentials(c.email, c.password); len(errs) != c.want {
+			t.Errorf("validateCredentials(%q, %q) = %v, want %d errors", c.email, c.password, errs, c.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/database"
+)
+
+// chirpVisibilities are the values accepted for a chirp's visibility.
+var chirpVisibilities = map[string]database.ChirpVisibility{
+	"public":    database.ChirpVisibilityPublic,
+	"followers": database.ChirpVisibilityFollowers,
+	"private":   database.ChirpVisibilityPrivate,
+}
+
+// viewer identifies who is reading, if anyone. Reads don't require a token,
+// so a missing or bad one just means an anonymous reader.
+func (a *apiConfig) viewer(req *http.Request) uuid.NullUUID {
+	if req.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}
+	}
+
+	userID, err := a.authenticate(req)
+	if err != nil {
+		log.Printf("in viewer, reading anonymously: %v", err)
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// canView reports whether viewer may see chirp: anyone for public chirps,
+// only the author for private ones, and followers of the author for the
+// rest, and nobody while the author is deactivated or once the chirp has
+// expired. This matches the filter in ListChirps for single chirp lookups.
+func canView(ctx context.Context, q database.Querier, viewer uuid.NullUUID, chirp database.Chirp) (bool, error) {
+	if chirp.ExpiresAt.Valid && !chirp.ExpiresAt.Time.After(time.Now()) {
+		return false, nil
+	}
+
+	author, err := q.GetUser(ctx, chirp.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case author.DeactivatedAt.Valid:
+		return false, nil
+	case chirp.Visibility == database.ChirpVisibilityPublic:
+		return true, nil
+	case !viewer.Valid:
+		return false, nil
+	case chirp.UserID == viewer.UUID:
+		return true, nil
+	case chirp.Visibility == database.ChirpVisibilityFollowers:
+		return q.IsFollowing(ctx, database.IsFollowingParams{
+			FollowerID: viewer.UUID,
+			FolloweeID: chirp.UserID,
+		})
+	}
+	return false, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookQueueSize  = 100
+	webhookMaxRetries = 3
+	webhookTimeout    = 10 * time.Second
+)
+
+// webhookDispatcher POSTs events to an outside URL from a background
+// goroutine, so a slow receiver never holds up a request. Each body is
+// signed with HMAC-SHA256 of the secret in X-Chirpy-Signature. A nil
+// dispatcher drops everything, which is how webhooks are turned off.
+type webhookDispatcher struct {
+	url    string
+	secret []byte
+	client *http.Client
+	queue  chan []byte
+}
+
+type webhookEvent struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+func newWebhookDispatcher(url, secret string) *webhookDispatcher {
+	d := &webhookDispatcher{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: webhookTimeout},
+		queue:  make(chan []byte, webhookQueueSize),
+	}
+	go d.run()
+	return d
+}
+
+// send queues an event without blocking. If the queue is full the event is
+// dropped and logged.
+func (d *webhookDispatcher) send(event string, data any) {
+	if d == nil {
+		return
+	}
+
+	body, err := json.Marshal(webhookEvent{Event: event, Data: data})
+	if err != nil {
+		log.Printf("in webhookDispatcher, unable to encode %s: %v", event, err)
+		return
+	}
+
+	select {
+	case d.queue <- body:
+	default:
+		log.Printf("in webhookDispatcher, queue full, dropped %s", event)
+	}
+}
+
+func (d *webhookDispatcher) run() {
+	for body := range d.queue {
+		backoff := time.Second
+		for attempt := 1; ; attempt++ {
+			err := d.post(body)
+			if err == nil {
+				break
+			}
+			if attempt > webhookMaxRetries {
+				log.Printf("in webhookDispatcher, giving up after %d attempts: %v", attempt, err)
+				break
+			}
+			log.Printf("in webhookDispatcher, attempt %d failed, retrying in %v: %v", attempt, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+func (d *webhookDispatcher) post(body []byte) error {
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write(body)
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Chirpy-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kbm-ky/chirpy/internal/auth"
+)
+
+// handlerWhoami decodes the bearer token without touching the database, to
+// debug auth problems. It is only routed with DEBUG on, as it tells anyone
+// why their token was refused.
+func (a *apiConfig) handlerWhoami(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, a.jwtKeys, a.jwtIssuer, "")
+	if errors.Is(err, auth.ErrTokenExpired) {
+		respondWithError(w, http.StatusUnauthorized, "Token is expired")
+		return
+	}
+	if err != nil {
+		log.Printf("in handlerWhoami, invalid token: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Token is invalid: "+err.Error())
+		return
+	}
+
+	type whoamiResponse struct {
+		Subject     uuid.UUID `json:"subject"`
+		Issuer      string    `json:"issuer"`
+		Audience    []string  `json:"audience"`
+		IsChirpyRed bool      `json:"is_chirpy_red"`
+		IssuedAt    time.Time `json:"issued_at"`
+		ExpiresAt   time.Time `json:"expires_at"`
+	}
+	respondWithJSON(w, http.StatusOK, whoamiResponse{
+		Subject:     claims.UserID,
+		Issuer:      claims.Issuer,
+		Audience:    claims.Audience,
+		IsChirpyRed: claims.IsChirpyRed,
+		IssuedAt:    claims.IssuedAt,
+		ExpiresAt:   claims.ExpiresAt,
+	})
+}